@@ -0,0 +1,287 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/dustin/go-humanize"
+	"github.com/pkg/errors"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// The card has the size of link previews on most social networks
+const (
+	cardWidth  = 1200
+	cardHeight = 630
+
+	cardMargin        = 60
+	maxCardNameLength = 40
+)
+
+// The card colors match the terminal colors of the report
+var (
+	cardBackground = color.RGBA{0x11, 0x14, 0x18, 0xff}
+	cardText       = color.RGBA{0xe6, 0xe8, 0xeb, 0xff}
+	cardGray       = color.RGBA{0x8b, 0x94, 0x9e, 0xff}
+	cardGreen      = color.RGBA{0x3f, 0xb9, 0x50, 0xff}
+	cardRed        = color.RGBA{0xf8, 0x51, 0x49, 0xff}
+	cardYellow     = color.RGBA{0xd2, 0x99, 0x22, 0xff}
+)
+
+// cardColor colors decreases green and increases red, like deltaColor.
+func cardColor(delta int64) color.RGBA {
+	if delta < 0 {
+		return cardGreen
+	} else if delta > 0 {
+		return cardRed
+	}
+
+	return cardGray
+}
+
+// cardLine is a line of text on the card. Y is the baseline.
+type cardLine struct {
+	X, Y  int
+	Size  float64
+	Bold  bool
+	Color color.RGBA
+	Text  string
+}
+
+// cardLayout places the summary of the report on the card: the package name,
+// and the size, subdependencies and traffic before and after.
+func cardLayout(r *Report) []cardLine {
+	s := r.Statistics
+
+	title := r.Title
+	if title == "" {
+		title = "Package size report"
+	}
+
+	lines := []cardLine{
+		{X: cardMargin, Y: 110, Size: 32, Color: cardGray, Text: title},
+		{X: cardMargin, Y: 190, Size: 60, Bold: true, Color: cardYellow, Text: truncateCardText(reportSubject(r), maxCardNameLength)},
+	}
+
+	if changes := cardChanges(r); changes != "" {
+		lines = append(lines, cardLine{X: cardMargin, Y: 250, Size: 28, Color: cardText, Text: truncateCardText(changes, 70)})
+	}
+
+	sizeDelta := int64(s.NewSize) - int64(s.OldSize)
+	subdepsDelta := int64(s.NewSubdependencies) - int64(s.OldSubdependencies)
+
+	trafficValue := "N/A"
+	trafficChange := "unknown downloads"
+	trafficProjection := ""
+	trafficColor := cardGray
+	if s.Traffic.Old != nil {
+		saved := s.Traffic.Saved()
+		trafficValue = humanize.BigBytes(s.Traffic.New)
+		trafficColor = cardColor(int64(-saved.Sign()))
+
+		switch saved.Sign() {
+		case 0:
+			trafficChange = "no change"
+		case 1:
+			trafficChange = humanize.BigBytes(saved) + " saved"
+		default:
+			trafficChange = humanize.BigBytes(saved.Neg(saved)) + " wasted"
+		}
+	}
+	if yearly, ok := yearlyProjection(s.AllVersionsSavings()); ok && yearly.Saved.Sign() != 0 {
+		trafficProjection = fmt.Sprintf("%s/year", fmtBigBytesAbs(yearly.Saved))
+		if yearly.Cost != nil {
+			trafficProjection += fmt.Sprintf(", %s/year", fmtMoney(abs(*yearly.Cost)))
+		}
+	}
+
+	columns := []struct {
+		Label  string
+		Value  string
+		Change string
+		Color  color.RGBA
+	}{
+		{
+			Label:  "Install size",
+			Value:  fmt.Sprintf("%s → %s", humanize.Bytes(s.OldSize), humanize.Bytes(s.NewSize)),
+			Change: fmt.Sprintf("%s (%s%%)", signedCardBytes(sizeDelta), fmtPercent(s.PercentSize)),
+			Color:  cardColor(sizeDelta),
+		},
+		{
+			Label:  "Subdependencies",
+			Value:  fmt.Sprintf("%s → %s", fmtInt(int64(s.OldSubdependencies)), fmtInt(int64(s.NewSubdependencies))),
+			Change: fmt.Sprintf("%+d", subdepsDelta),
+			Color:  cardColor(subdepsDelta),
+		},
+		{
+			Label:  "Traffic last week",
+			Value:  trafficValue,
+			Change: trafficChange,
+			Color:  trafficColor,
+		},
+	}
+
+	columnWidth := (cardWidth - 2*cardMargin) / len(columns)
+	for i, c := range columns {
+		x := cardMargin + i*columnWidth
+		lines = append(lines,
+			cardLine{X: x, Y: 390, Size: 26, Color: cardGray, Text: c.Label},
+			cardLine{X: x, Y: 445, Size: 36, Bold: true, Color: cardText, Text: c.Value},
+			cardLine{X: x, Y: 495, Size: 30, Bold: true, Color: c.Color, Text: c.Change},
+		)
+	}
+
+	if trafficProjection != "" {
+		x := cardMargin + (len(columns)-1)*columnWidth
+		lines = append(lines, cardLine{X: x, Y: 535, Size: 24, Color: trafficColor, Text: trafficProjection + " across versions"})
+	}
+
+	lines = append(lines, cardLine{X: cardMargin, Y: cardHeight - cardMargin, Size: 22, Color: cardGray, Text: "package-size-calculator"})
+
+	return lines
+}
+
+// cardChanges summarizes the removed and added dependencies and overrides.
+func cardChanges(r *Report) string {
+	var changes []string
+	for _, d := range r.Removed {
+		changes = append(changes, "− "+d.Name)
+	}
+	for _, d := range r.Added {
+		changes = append(changes, "+ "+d.Name)
+	}
+	for _, o := range r.Overrides {
+		changes = append(changes, "~ "+o.Name)
+	}
+
+	return strings.Join(changes, "   ")
+}
+
+func truncateCardText(s string, maxLength int) string {
+	runes := []rune(s)
+	if len(runes) <= maxLength {
+		return s
+	}
+
+	return string(runes[:maxLength-1]) + "…"
+}
+
+func signedCardBytes(delta int64) string {
+	if delta < 0 {
+		return "−" + humanize.Bytes(uint64(-delta))
+	}
+
+	return "+" + humanize.Bytes(uint64(delta))
+}
+
+// svgCardRenderer draws a summary card of the report as an SVG, ready to be
+// attached to posts.
+type svgCardRenderer struct{}
+
+func (svgCardRenderer) Render(out io.Writer, r *Report) error {
+	w := &errWriter{w: out}
+
+	fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", cardWidth, cardHeight, cardWidth, cardHeight)
+	fmt.Fprintf(w, `  <rect width="100%%" height="100%%" fill="%s"/>`+"\n", svgColor(cardBackground))
+	fmt.Fprintf(w, `  <rect width="%d" height="100%%" fill="%s"/>`+"\n", 12, svgColor(cardColor(int64(r.Statistics.NewSize)-int64(r.Statistics.OldSize))))
+
+	for _, l := range cardLayout(r) {
+		weight := "normal"
+		if l.Bold {
+			weight = "bold"
+		}
+
+		fmt.Fprintf(
+			w,
+			`  <text x="%d" y="%d" font-family="Go, system-ui, sans-serif" font-size="%g" font-weight="%s" fill="%s">`,
+			l.X, l.Y, l.Size, weight, svgColor(l.Color),
+		)
+		if w.err == nil {
+			w.err = xml.EscapeText(w, []byte(l.Text))
+		}
+		fmt.Fprintln(w, "</text>")
+	}
+
+	fmt.Fprintln(w, "</svg>")
+
+	return w.err
+}
+
+func svgColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// pngCardRenderer draws the same card as svgCardRenderer as a PNG, for
+// networks that don't accept SVGs.
+type pngCardRenderer struct{}
+
+func (pngCardRenderer) Render(w io.Writer, r *Report) error {
+	img := image.NewRGBA(image.Rect(0, 0, cardWidth, cardHeight))
+	draw.Draw(img, img.Bounds(), image.NewUniform(cardBackground), image.Point{}, draw.Src)
+
+	accent := cardColor(int64(r.Statistics.NewSize) - int64(r.Statistics.OldSize))
+	draw.Draw(img, image.Rect(0, 0, 12, cardHeight), image.NewUniform(accent), image.Point{}, draw.Src)
+
+	for _, l := range cardLayout(r) {
+		face, err := cardFace(l.Size, l.Bold)
+		if err != nil {
+			return err
+		}
+
+		d := font.Drawer{
+			Dst:  img,
+			Src:  image.NewUniform(l.Color),
+			Face: face,
+			Dot:  fixed.P(l.X, l.Y),
+		}
+		d.DrawString(l.Text)
+	}
+
+	return png.Encode(w, img)
+}
+
+var (
+	cardFontsOnce sync.Once
+	cardFonts     map[bool]*opentype.Font
+	cardFontsErr  error
+)
+
+// cardFace returns a face of the regular or bold Go font in the size.
+func cardFace(size float64, bold bool) (font.Face, error) {
+	cardFontsOnce.Do(func() {
+		regular, err := opentype.Parse(goregular.TTF)
+		if err != nil {
+			cardFontsErr = errors.Wrap(err, "failed to parse regular font")
+			return
+		}
+
+		boldFont, err := opentype.Parse(gobold.TTF)
+		if err != nil {
+			cardFontsErr = errors.Wrap(err, "failed to parse bold font")
+			return
+		}
+
+		cardFonts = map[bool]*opentype.Font{false: regular, true: boldFont}
+	})
+	if cardFontsErr != nil {
+		return nil, cardFontsErr
+	}
+
+	face, err := opentype.NewFace(cardFonts[bold], &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create font face")
+	}
+
+	return face, nil
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"math/big"
+	"strconv"
+	"time"
+)
+
+// csvHeader are the columns of CSV and TSV reports. Sizes and traffic are in
+// bytes, costs in USD, empty cells are unknown. The projected savings, their
+// costs and energy are only set in the row of the modified package or new
+// version, and only if they were requested.
+var csvHeader = []string{
+	"report",
+	"generated_at",
+	"type",
+	"name",
+	"version",
+	"size",
+	"subdependencies",
+	"downloads_last_week",
+	"total_downloads",
+	"traffic_last_week",
+	"percent_downloads_of_version",
+	"percent_of_size",
+	"percent_of_subdependencies",
+	"percent_of_traffic",
+	"saved_traffic_month",
+	"saved_traffic_quarter",
+	"saved_traffic_year",
+	"saved_egress_cost_month",
+	"saved_egress_cost_quarter",
+	"saved_egress_cost_year",
+	"saved_energy_kwh_year",
+	"saved_kg_co2e_year",
+}
+
+// csvRenderer writes one row per measured package and dependency for
+// spreadsheets. The report column groups the rows of a report, so that batch
+// runs can append to the same file.
+type csvRenderer struct {
+	Comma rune
+	// SkipHeader is set when appending to a file that already has one
+	SkipHeader bool
+}
+
+// csvRow is the measurements of a package in a report.
+type csvRow struct {
+	Type                      string
+	Name                      string
+	Version                   string
+	Size                      uint64
+	Subdependencies           uint64
+	DownloadsLastWeek         *uint64
+	TotalDownloads            uint64
+	TrafficLastWeek           *big.Int
+	PercentDownloadsOfVersion *float64
+	PercentOfSize             *float64
+	PercentOfSubdependencies  *float64
+	PercentOfTraffic          *float64
+	// Projections are in the order of trafficPeriods, empty if the downloads
+	// are unknown
+	Projections []trafficProjection
+	Energy      *energyEstimate
+}
+
+func (c csvRenderer) Render(out io.Writer, r *Report) error {
+	w := csv.NewWriter(out)
+	w.Comma = c.Comma
+
+	if !c.SkipHeader {
+		if err := w.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+
+	subject := reportSubject(r)
+	generatedAt := r.GeneratedAt.UTC().Format(time.RFC3339)
+
+	for _, row := range csvRows(r) {
+		record := []string{
+			subject,
+			generatedAt,
+			row.Type,
+			row.Name,
+			row.Version,
+			strconv.FormatUint(row.Size, 10),
+			strconv.FormatUint(row.Subdependencies, 10),
+			csvUint(row.DownloadsLastWeek),
+			strconv.FormatUint(row.TotalDownloads, 10),
+			csvBigInt(row.TrafficLastWeek),
+			csvPercent(row.PercentDownloadsOfVersion),
+			csvPercent(row.PercentOfSize),
+			csvPercent(row.PercentOfSubdependencies),
+			csvPercent(row.PercentOfTraffic),
+		}
+		record = append(record, csvSavings(row)...)
+
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+
+	return w.Error()
+}
+
+// csvRows flattens the report into the measured packages: the package or the
+// old and new version, the removed and added dependencies and the modified
+// package.
+func csvRows(r *Report) []csvRow {
+	var rows []csvRow
+
+	for i, p := range r.Packages {
+		row := csvPackageRow(p)
+		switch {
+		case len(r.Packages) == 2 && i == 0:
+			row.Type = "old"
+		case len(r.Packages) == 2:
+			row.Type = "new"
+			row.Projections = r.Statistics.Traffic.Projections
+			if r.Energy != nil {
+				row.Energy = r.Energy.Current
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	for _, d := range r.Removed {
+		rows = append(rows, csvDependencyRow("removed", d))
+	}
+	for _, d := range r.Added {
+		rows = append(rows, csvDependencyRow("added", d))
+	}
+
+	// The new version is already in the rows of version reports
+	if len(r.Packages) == 1 {
+		p := r.Packages[0]
+		s := r.Statistics
+		percentOfSize := s.PercentSize
+		percentOfSubdependencies := calculatePercentage(float64(s.NewSubdependencies), float64(s.OldSubdependencies))
+
+		row := csvRow{
+			Type:                      "modified",
+			Name:                      p.PackageName,
+			Version:                   p.Version,
+			Size:                      s.NewSize,
+			Subdependencies:           s.NewSubdependencies,
+			DownloadsLastWeek:         p.Stats.DownloadsLastWeek,
+			TotalDownloads:            p.Stats.TotalDownloads,
+			TrafficLastWeek:           s.Traffic.New,
+			PercentDownloadsOfVersion: p.Stats.PercentDownloadsOfVersion,
+			PercentOfSize:             &percentOfSize,
+			PercentOfSubdependencies:  &percentOfSubdependencies,
+			Projections:               s.Traffic.Projections,
+		}
+		if r.Energy != nil {
+			row.Energy = r.Energy.Current
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+func csvPackageRow(p packageSummary) csvRow {
+	row := csvRow{
+		Type:                      "package",
+		Name:                      p.PackageName,
+		Version:                   p.Version,
+		Size:                      p.Stats.Size,
+		Subdependencies:           p.Stats.Subdependencies,
+		DownloadsLastWeek:         p.Stats.DownloadsLastWeek,
+		TotalDownloads:            p.Stats.TotalDownloads,
+		PercentDownloadsOfVersion: p.Stats.PercentDownloadsOfVersion,
+	}
+	if p.Stats.TrafficLastWeek != nil {
+		row.TrafficLastWeek = new(big.Int).SetUint64(*p.Stats.TrafficLastWeek)
+	}
+
+	return row
+}
+
+func csvDependencyRow(typ string, d dependencySummary) csvRow {
+	row := csvRow{
+		Type:                      typ,
+		Name:                      d.PackageName,
+		Version:                   d.Version,
+		Size:                      d.Stats.Size,
+		Subdependencies:           d.Stats.Subdependencies,
+		DownloadsLastWeek:         d.Stats.DownloadsLastWeek,
+		TotalDownloads:            d.Stats.TotalDownloads,
+		PercentDownloadsOfVersion: d.Stats.PercentDownloadsOfVersion,
+		PercentOfSize:             &d.PercentOfSize,
+		PercentOfSubdependencies:  &d.PercentOfSubdependencies,
+		PercentOfTraffic:          d.PercentOfTraffic,
+	}
+	if d.Stats.TrafficLastWeek != nil {
+		row.TrafficLastWeek = new(big.Int).SetUint64(*d.Stats.TrafficLastWeek)
+	}
+
+	return row
+}
+
+// csvSavings are the cells of the projected traffic, costs and energy a
+// modification saves.
+func csvSavings(row csvRow) []string {
+	traffic := make([]string, len(trafficPeriods))
+	costs := make([]string, len(trafficPeriods))
+	for i, p := range row.Projections {
+		traffic[i] = csvBigInt(p.Saved)
+		if p.Cost != nil {
+			costs[i] = strconv.FormatFloat(*p.Cost, 'f', 2, 64)
+		}
+	}
+
+	energy := []string{"", ""}
+	if row.Energy != nil {
+		energy = []string{
+			strconv.FormatFloat(row.Energy.TotalKWh(), 'f', 3, 64),
+			strconv.FormatFloat(row.Energy.KgCO2e, 'f', 3, 64),
+		}
+	}
+
+	return append(append(traffic, costs...), energy...)
+}
+
+func csvUint(v *uint64) string {
+	if v == nil {
+		return ""
+	}
+
+	return strconv.FormatUint(*v, 10)
+}
+
+func csvBigInt(v *big.Int) string {
+	if v == nil {
+		return ""
+	}
+
+	return v.String()
+}
+
+func csvPercent(v *float64) string {
+	if v == nil {
+		return ""
+	}
+
+	return strconv.FormatFloat(*v, 'f', 2, 64)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+)
+
+func TestCSVRendererSavings(t *testing.T) {
+	downloads := uint64(1000)
+	summary := packageSummary{
+		Name:        "pkg@1.0.0",
+		PackageName: "pkg",
+		Version:     "1.0.0",
+		Stats:       calculatedStats{Size: 1e6, DownloadsLastWeek: &downloads, TotalDownloads: downloads},
+	}
+
+	tests := []struct {
+		name   string
+		price  *egressPrice
+		energy *energyModel
+		// want are the savings cells of the modified row
+		want map[string]string
+	}{
+		{
+			name: "savings only",
+			want: map[string]string{
+				"saved_traffic_year":     "26071428571",
+				"saved_egress_cost_year": "",
+				"saved_energy_kwh_year":  "",
+				"saved_kg_co2e_year":     "",
+			},
+		},
+		{
+			name:   "with costs and energy",
+			price:  &egressPrice{PerGB: 0.1},
+			energy: &energyModel{NetworkKWhPerGB: 1, GramsCO2ePerKWh: 1000},
+			want: map[string]string{
+				"saved_traffic_month":    "2172619047",
+				"saved_egress_cost_year": "2.61",
+				"saved_energy_kwh_year":  "26.071",
+				"saved_kg_co2e_year":     "26.071",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prevPrice := activeEgressPrice
+			activeEgressPrice = tt.price
+			defer func() { activeEgressPrice = prevPrice }()
+
+			stats := newStatisticsChange(1e6, 5e5, &downloads, downloads, 0, 0, nil)
+			r := &Report{
+				GeneratedAt: time.Unix(0, 0),
+				Packages:    []packageSummary{summary},
+				Statistics:  stats,
+				EgressPrice: tt.price,
+				Energy:      newEnergyChange(stats, tt.energy),
+			}
+
+			var out bytes.Buffer
+			if err := (csvRenderer{Comma: ','}).Render(&out, r); err != nil {
+				t.Fatal(err)
+			}
+
+			records, err := csv.NewReader(&out).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(records) != 3 {
+				t.Fatalf("got %d records, want the header, package and modified row", len(records))
+			}
+
+			cells := func(record []string) map[string]string {
+				m := make(map[string]string, len(record))
+				for i, column := range records[0] {
+					m[column] = record[i]
+				}
+
+				return m
+			}
+
+			pkg := cells(records[1])
+			if pkg["saved_traffic_year"] != "" || pkg["saved_energy_kwh_year"] != "" {
+				t.Errorf("package row has savings: %v", records[1])
+			}
+
+			modified := cells(records[2])
+			if modified["type"] != "modified" {
+				t.Fatalf("last row is %q, want modified", modified["type"])
+			}
+			for column, want := range tt.want {
+				if got := modified[column]; got != want {
+					t.Errorf("%s = %q, want %q", column, got, want)
+				}
+			}
+		})
+	}
+}
@@ -1,14 +1,15 @@
 package main
 
 import (
+	"fmt"
 	"math"
 	"package_size_calculator/internal"
 	"package_size_calculator/pkg/npm"
 	"package_size_calculator/pkg/ui_components"
+	"path/filepath"
 	"strings"
 	"sync"
 
-	npm_version "github.com/aquasecurity/go-npm-version/pkg"
 	"github.com/dustin/go-humanize"
 	"github.com/manifoldco/promptui"
 	"github.com/rs/zerolog/log"
@@ -23,10 +24,24 @@ func replaceDeps() {
 		log.Fatal().Err(err).Msg("Failed to run editable list")
 	}
 
+	overrides, err := ui_components.NewEditableList("Overrides", parseOverride).Run()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to run editable list")
+	}
+
 	deps := combineDependencies(removedDependencies, addedDependencies)
+	overrideInfos := combineOverrides(overrides)
 
 	statistics := &ModifiedStats{}
 
+	graph := npm.NewGraph(pkg.Lockfile)
+	for _, d := range removedDependencies {
+		exclusive := calculateExclusiveStats(graph, pkg.PackageSizes, pkg.Package.JSON.Name, []npm.DependencyInfo{d})
+		deps[d.String()].Exclusive = &exclusive
+	}
+	pkg.ExclusiveOfRemoved = calculateExclusiveStats(graph, pkg.PackageSizes, pkg.Package.JSON.Name, removedDependencies)
+	baseline := &ModifiedStats{}
+
 	wg := sync.WaitGroup{}
 	wg.Add(1)
 
@@ -38,34 +53,80 @@ func replaceDeps() {
 			addedAsDeps = append(addedAsDeps, d.AsDependency())
 		}
 
-		tmpDir, err := modifyPackage(pkg.Package.JSON, addedAsDeps, removedDependencies)
+		var err error
+		*statistics, err = measureModifiedPackage(pkg.Manifest, addedAsDeps, removedDependencies, overrides)
 		if err != nil {
-			log.Fatal().Err(err).Msg("Failed to modify package")
+			log.Fatal().Err(err).Msg("Failed to measure modified package")
 		}
-		if !*fNoCleanup {
-			defer tmpDir.Remove()
+	}()
+
+	if len(overrides) > 0 {
+		// The effect of an override can only be compared to the unmodified
+		// package installed the same way
+		wg.Add(1 + len(overrideInfos))
+
+		go func() {
+			defer wg.Done()
+
+			var err error
+			*baseline, err = measureModifiedPackage(pkg.Manifest, nil, nil, nil)
+			if err != nil {
+				log.Fatal().Err(err).Msg("Failed to measure unmodified package")
+			}
+		}()
+
+		for _, o := range overrideInfos {
+			go func(o *overrideInfo) {
+				defer wg.Done()
+
+				var err error
+				o.ModifiedStats, err = measureModifiedPackage(pkg.Manifest, nil, nil, []npm.Override{o.Override})
+				if err != nil {
+					log.Fatal().Err(err).Str("override", o.String()).Msg("Failed to measure package with override")
+				}
+			}(o)
 		}
+	}
 
-		statistics.Size, err = internal.DirSize(tmpDir.Join("node_modules"))
-		if err != nil {
-			log.Fatal().Err(err).Msg("Failed to measure new package size")
+	// Only dependencies with a version need the per-version downloads, the
+	// totals of the others are fetched in bulk
+	var versionedNames, unversionedNames []string
+	for _, dep := range deps {
+		if !dep.SpecType().IsRegistry() {
+			continue
 		}
 
-		lock, err := npm.ParsePackageLockJSON(tmpDir.Join("package-lock.json"))
-		if err != nil {
-			log.Fatal().Err(err).Msg("Failed to parse new package-lock.json")
+		if dep.Version != "" {
+			versionedNames = append(versionedNames, dep.RegistryName())
+		} else {
+			unversionedNames = append(unversionedNames, dep.RegistryName())
 		}
+	}
 
-		statistics.Subdependencies = uint64(len(lock.Packages))
-	}()
+	allDownloads, err := npmClient.GetPackagesDownloadsLastWeek(versionedNames)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to fetch downloads of some packages")
+	}
+
+	allTotals, err := npmClient.GetPackagesDownloadTotalsLastWeek(unversionedNames)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to fetch downloads of some packages")
+	}
 
 	wg.Add(len(deps))
 	for depName, dep := range deps {
 		l := log.With().Str("package", depName).Logger()
 
-		downloads, err := npmClient.GetPackageDownloadsLastWeek(dep.Name)
-		if err != nil {
-			l.Error().Err(err).Msg("Failed to fetch package downloads")
+		if !dep.SpecType().IsRegistry() {
+			l.Debug().Stringer("type", dep.SpecType()).Msg("Dependency isn't published to the registry, skipping downloads")
+		} else if dep.Version == "" {
+			if total, ok := allTotals[dep.RegistryName()]; ok {
+				dep.TotalDownloads = total
+			} else {
+				l.Error().Msg("Failed to fetch package downloads")
+			}
+		} else if downloads, ok := allDownloads[dep.RegistryName()]; !ok {
+			l.Error().Msg("Failed to fetch package downloads")
 		} else {
 			dlsLastWeek, ok := downloads.ForVersion(dep.Version)
 			if ok {
@@ -75,10 +136,22 @@ func replaceDeps() {
 			dep.TotalDownloads = downloads.Total()
 		}
 
+		if dep.SpecType().IsLocal() {
+			l.Warn().Stringer("type", dep.SpecType()).Msg("Can't measure dependencies from the local filesystem")
+			wg.Done()
+			continue
+		}
+		if dep.SpecType() == npm.SpecTypeInvalid {
+			l.Warn().Str("spec", dep.RawSpec()).Msg("Can't measure dependencies with an invalid spec")
+			wg.Done()
+			continue
+		}
+
 		go func(dep *dependencyPackageInfo) {
 			defer wg.Done()
 
 			var tmpDir internal.TmpDir
+			var err error
 			dep.Size, tmpDir, err = measurePackageSize(dep.DependencyInfo)
 			if err != nil {
 				l.Fatal().Err(err).Msg("Failed to measure package size")
@@ -92,6 +165,7 @@ func replaceDeps() {
 				l.Error().Err(err).Msg("Failed to parse package-lock.json")
 			} else {
 				dep.Subdependencies = getSubdependenciesCount(lock)
+				dep.Deprecated = findDeprecated(lock)
 			}
 
 			l.Info().Msgf("Package size: %s", humanize.Bytes(dep.Size))
@@ -99,56 +173,59 @@ func replaceDeps() {
 	}
 	wg.Wait()
 
-	printReport(pkg, statistics, removedDependencies, addedDependencies, deps)
+	r := newReplacementReport(pkg, statistics, removedDependencies, addedDependencies, deps, baseline, overrideInfos)
+	if err := writeReport(r); err != nil {
+		log.Fatal().Err(err).Msg("Failed to write report")
+	}
 }
 
 func resolveNPMPackage(client *npm.Client) ui_components.StringToItemConvertFunc[npm.PackageJSON] {
 	return func(s string) (npm.PackageJSON, error) {
 		l := log.With().Str("package", s).Logger()
 
-		split := strings.SplitN(s, " ", 2)
-		log.Trace().Strs("split", split).Msg("Split package")
-
-		if len(split) == 1 && strings.Contains(s, "@") && !strings.HasPrefix(s, "@") {
-			split = strings.SplitN(s, "@", 2)
+		spec, err := npm.ParseSpec(s)
+		if err != nil {
+			l.Error().Err(err).Msg("Failed to parse package spec")
+			return npm.PackageJSON{}, ui_components.ErrRetry
 		}
+		l.Trace().Str("name", spec.Name).Stringer("type", spec.Type).Str("fetchSpec", spec.FetchSpec).Msg("Parsed package spec")
 
 		log.Info().Msgf("Resolving package \"%s\"...", s)
 
-		info, err := client.GetPackageInfo(split[0])
+		_, version, err := client.ResolveSpec(spec)
 		if err != nil {
-			return npm.PackageJSON{}, err
+			l.Error().Err(err).Msg("No matching version could be found")
+			return npm.PackageJSON{}, ui_components.ErrRetry
 		}
 
-		if len(split) == 1 {
-			l.Trace().Msg("No constraint specified, using latest version")
-
-			latest := info.LatestVersion.JSON
-			l.Info().Str("version", latest.Version).Msg("Found latest version")
-
-			return latest, nil
-		}
+		l.Info().Str("version", version.JSON.Version).Msg("Found version")
 
-		constraint := split[1]
-		l = log.With().Str("constraint", constraint).Logger()
-		l.Trace().Msg("Resolving constraint")
+		return version.JSON, nil
+	}
+}
 
-		c, err := npm_version.NewConstraints(constraint)
-		if err != nil {
-			log.Error().Err(err).Msg("Failed to create constraints")
-			return npm.PackageJSON{}, ui_components.ErrRetry
-		}
+func parseOverride(s string) (npm.Override, error) {
+	o, err := npm.ParseOverride(s)
+	if err != nil {
+		log.Error().Err(err).Str("override", s).Msg("Failed to parse override")
+		return npm.Override{}, ui_components.ErrRetry
+	}
 
-		version := info.Versions.Match(c)
-		if version == nil {
-			log.Error().Msg("No matching version could be found")
-			return npm.PackageJSON{}, ui_components.ErrRetry
-		}
+	return o, nil
+}
 
-		l.Info().Str("version", version.JSON.Version).Msg("Found version")
+type overrideInfo struct {
+	npm.Override
+	ModifiedStats
+}
 
-		return version.JSON, nil
+func combineOverrides(overrides []npm.Override) []*overrideInfo {
+	infos := make([]*overrideInfo, len(overrides))
+	for i, o := range overrides {
+		infos[i] = &overrideInfo{Override: o}
 	}
+
+	return infos
 }
 
 type dependencyPackageInfoType uint8
@@ -162,6 +239,12 @@ type dependencyPackageInfo struct {
 	npm.DependencyInfo
 	calculatedStats
 	Type dependencyPackageInfoType
+	// Exclusive is what removing the dependency saves in the package's tree,
+	// only set for removed dependencies
+	Exclusive *exclusiveStats
+	// Deprecated are the deprecated packages in the dependency's own tree,
+	// including the dependency itself
+	Deprecated []npm.DeprecatedPackage
 }
 
 func combineDependencies(removedDependencies []npm.DependencyInfo, addedDependencies []npm.PackageJSON) map[string]*dependencyPackageInfo {
@@ -198,35 +281,90 @@ func combineDependencies(removedDependencies []npm.DependencyInfo, addedDependen
 	return deps
 }
 
+// removableDependency is a dependency of the package that can be removed,
+// labeled with its kind if it isn't a regular dependency.
+type removableDependency struct {
+	npm.DependencyInfo
+	Kind npm.DependencyKind
+}
+
+func (d removableDependency) String() string {
+	if d.Kind == npm.DependencyKindProd {
+		return d.DependencyInfo.String()
+	}
+
+	return fmt.Sprintf("%s (%s)", d.DependencyInfo.String(), d.Kind)
+}
+
 func promptRemovedDependencies(packageJson npm.PackageJSON, pkgLock *npm.PackageLockJSON) []npm.DependencyInfo {
-	dependencies := make([]npm.DependencyInfo, 0, len(packageJson.Dependencies))
-	for _, k := range packageJson.Dependencies {
-		dep, ok := pkgLock.Packages[k.Name]
-		if !ok {
-			log.Warn().Str("dependency", k.Name).Msg("Dependency not found")
-			continue
-		}
+	var dependencies []removableDependency
+	seen := map[string]struct{}{}
+
+	// Dependencies that are also listed as optional or peer dependencies are
+	// installed as such, so they're labeled with that kind
+	for _, kind := range []npm.DependencyKind{npm.DependencyKindPeer, npm.DependencyKindOptional, npm.DependencyKindProd} {
+		for _, k := range packageJson.DependenciesOfKind(kind) {
+			if _, ok := seen[k.Name]; ok {
+				continue
+			}
+			seen[k.Name] = struct{}{}
+
+			dep, ok := pkgLock.Packages[k.Name]
+			if !ok && k.Spec.Type.IsRegistry() {
+				log.Warn().Str("dependency", k.Name).Stringer("kind", kind).Msg("Dependency not found")
+				continue
+			} else if !ok {
+				// Local dependencies can't be installed in the container, but they
+				// can still be removed
+				log.Debug().Str("dependency", k.String()).Msg("Dependency not installed")
+			}
 
-		dependencies = append(dependencies, dep.AsDependency())
+			dependencies = append(dependencies, removableDependency{DependencyInfo: k.AsDependency(dep), Kind: kind})
+		}
 	}
 
-	removedDependencies, err := ui_components.NewMultiSelect("Removed dependencies", dependencies).Run()
+	selected, err := ui_components.NewMultiSelect("Removed dependencies", dependencies).Run()
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to run multi select")
 	}
 
+	removedDependencies := make([]npm.DependencyInfo, len(selected))
+	for i, d := range selected {
+		removedDependencies[i] = d.DependencyInfo
+	}
+
 	return removedDependencies
 }
 
 func promptPackageVersion(packageInfo *npm.PackageInfo, label string) string {
 	versions := packageInfo.Versions.Sorted()
 
+	// Parsing the input for every version would be wasteful, so we remember
+	// the spec of the last input
+	var (
+		lastInput string
+		lastSpec  *npm.Spec
+	)
+
 	_, packageVersion, err := internal.RunSelect(&promptui.Select{
 		Label: label,
 		Items: versions,
 		Size:  int(math.Min(float64(len(versions)), 16)),
 		Searcher: func(input string, index int) bool {
-			return strings.Contains(versions[index].String(), input)
+			if input != lastInput {
+				lastInput = input
+				lastSpec = nil
+
+				if spec, err := npm.NewSpec(packageInfo.Name, input); err == nil {
+					lastSpec = &spec
+				}
+			}
+
+			if strings.Contains(versions[index].String(), input) {
+				return true
+			}
+
+			return lastSpec != nil && lastSpec.Matches(packageInfo, versions[index])
 		},
 	})
 	if err != nil {
@@ -236,27 +374,58 @@ func promptPackageVersion(packageInfo *npm.PackageInfo, label string) string {
 	return packageVersion
 }
 
-func promptPackage(npmClient *npm.Client) *packageInfo {
-	packageName, err := internal.RunPrompt(&promptui.Prompt{Label: "Package"})
-	if err != nil {
-		log.Fatal().Err(err).Msg("Prompt failed")
-	}
+// promptPackageSpec asks for a package spec until a valid one is entered and
+// returns it together with the package info. The version is nil if the spec
+// only consists of a name and has to be picked by the user.
+func promptPackageSpec(npmClient *npm.Client) (*npm.PackageInfo, *npm.PackageVersion) {
+	for {
+		input, err := internal.RunPrompt(&promptui.Prompt{Label: "Package"})
+		if err != nil {
+			log.Fatal().Err(err).Msg("Prompt failed")
+		}
 
-	log.Info().Str("package", packageName).Msg("Fetching package info")
+		spec, err := npm.ParseSpec(input)
+		if err != nil {
+			log.Error().Err(err).Str("package", input).Msg("Failed to parse package spec")
+			continue
+		}
 
-	b := &packageInfo{}
+		log.Info().Str("package", spec.RegistryName()).Msg("Fetching package info")
 
-	packageInfo, err := npmClient.GetPackageInfo(packageName)
-	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to fetch package info")
+		info, err := npmClient.GetPackageInfo(spec.RegistryName())
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to fetch package info")
+		}
+
+		log.Debug().Msgf("Fetched package info for %s", info.Name)
+
+		if spec.Target().RawSpec == "" {
+			return info, nil
+		}
+
+		version, err := info.Resolve(spec)
+		if err != nil {
+			log.Error().Err(err).Str("package", input).Msg("No matching version could be found")
+			continue
+		}
+
+		return info, version
 	}
+}
+
+func promptPackage(npmClient *npm.Client) *packageInfo {
+	b := &packageInfo{}
 
+	packageInfo, version := promptPackageSpec(npmClient)
 	b.Info = packageInfo
 
-	log.Debug().Msgf("Fetched package info for %s", packageInfo.Name)
+	if version != nil {
+		b.Package = *version
+	} else {
+		b.Package = packageInfo.Versions[promptPackageVersion(packageInfo, "Select version")]
+	}
 
-	packageVersion := promptPackageVersion(packageInfo, "Select version")
-	b.Package = packageInfo.Versions[packageVersion]
+	packageVersion := b.Package.Version.String()
 	log.Info().Str("version", packageVersion).Msg("Selected version")
 
 	downloads, err := npmClient.GetPackageDownloadsLastWeek(packageInfo.Name)
@@ -287,6 +456,18 @@ func promptPackage(npmClient *npm.Client) *packageInfo {
 		log.Fatal().Err(err).Msg("Failed to parse package-lock.json")
 	}
 
+	b.Manifest = readManifest(b.TmpDir, b.Package.JSON)
+
+	b.PackageSizes, err = measureLockedPackageSizes(b.TmpDir.String(), b.Lockfile)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to measure installed packages")
+	}
+	b.Kinds = calculateKindBreakdown(b.Lockfile, b.PackageSizes, b.Package.JSON.Name)
+	b.InstallScripts = findInstallScripts(b.Lockfile, b.PackageSizes)
+	b.Deprecated = findDeprecated(b.Lockfile)
+	b.Licenses = collectLicenses(b.Lockfile, b.Package.JSON.Name)
+	b.Tree = captureSizeTree(b.TmpDir)
+
 	b.Stats = stats{
 		TotalDownloads:    downloads.Total(),
 		DownloadsLastWeek: downloadsLastWeek,
@@ -294,15 +475,65 @@ func promptPackage(npmClient *npm.Client) *packageInfo {
 		Subdependencies:   getSubdependenciesCount(b.Lockfile),
 	}.Calculate()
 
+	if *fCompareScripts {
+		compareInstallScripts(b)
+	}
+
+	if *fCompareEstimate {
+		estimate := estimatePackageSize(b.Package.JSON)
+		b.Estimate = &estimate
+	}
+
+	if *fVersionMix > 0 {
+		b.VersionMix = measureVersionMix(packageInfo, downloads, map[string]uint64{packageVersion: size}, *fVersionMix)
+	}
+
 	return b
 }
 
 type packageInfo struct {
-	Info     *npm.PackageInfo
-	Package  npm.PackageVersion
+	Info    *npm.PackageInfo
+	Package npm.PackageVersion
+	// Manifest is the package.json of the installed package, which modified
+	// packages are written from
+	Manifest npm.PackageJSON
 	Lockfile *npm.PackageLockJSON
-	Stats    calculatedStats
-	TmpDir   internal.TmpDir
+	// PackageSizes maps the paths in the lockfile to the size of the package
+	// without its nested dependencies
+	PackageSizes map[string]uint64
+	// ExclusiveOfRemoved is what removing all removed dependencies saves,
+	// calculated from the lockfile once they're selected
+	ExclusiveOfRemoved exclusiveStats
+	Kinds              kindBreakdown
+	Stats              calculatedStats
+	// InstallScripts are the packages in the tree with install scripts
+	InstallScripts []installScript
+	// SizeWithoutScripts is only set if it was requested with -compare-scripts
+	SizeWithoutScripts *uint64
+	Deprecated         []npm.DeprecatedPackage
+	Licenses           licenseInventory
+	// Estimate is only set if it was requested with -compare-estimate
+	Estimate *sizeEstimate
+	// Tree is only set for the HTML report
+	Tree *sizeTree
+	// VersionMix is only set if it was requested with -version-mix
+	VersionMix *versionMix
+	TmpDir     internal.TmpDir
+}
+
+// readManifest reads the package.json of the installed package, so that
+// modified packages keep the fields the registry doesn't return. The registry's
+// version of the package is used if it can't be read.
+func readManifest(tmpDir internal.TmpDir, pkg npm.PackageJSON) npm.PackageJSON {
+	path := tmpDir.Join(filepath.Join("node_modules", pkg.Name, "package.json"))
+
+	manifest, err := npm.ParseEditablePackageJSON(path)
+	if err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("Failed to read installed package.json, fields unknown to the registry will be dropped")
+		return pkg
+	}
+
+	return manifest
 }
 
 func (b *packageInfo) String() string {
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"package_size_calculator/internal"
+	"package_size_calculator/pkg/npm"
+)
+
+type kindStats struct {
+	Packages uint64
+	Size     uint64
+}
+
+type kindBreakdown map[npm.DependencyKind]kindStats
+
+// calculateKindBreakdown groups the installed packages by the reason they are
+// in the tree. The installed package itself isn't counted as a package, but
+// its size is included in the production dependencies so the sizes add up to
+// the size of node_modules.
+func calculateKindBreakdown(lock *npm.PackageLockJSON, sizes map[string]uint64, installed string) kindBreakdown {
+	breakdown := kindBreakdown{}
+
+	for path, pkg := range lock.Packages {
+		kind := pkg.Kind()
+		s := breakdown[kind]
+
+		if path != installed {
+			s.Packages++
+		}
+		s.Size += sizes[path]
+
+		breakdown[kind] = s
+	}
+
+	return breakdown
+}
+
+// measureKindBreakdown measures the installed packages in tmpDir and groups
+// them by kind.
+func measureKindBreakdown(tmpDir internal.TmpDir, lock *npm.PackageLockJSON, installed string) (kindBreakdown, error) {
+	sizes, err := measureLockedPackageSizes(tmpDir.String(), lock)
+	if err != nil {
+		return nil, err
+	}
+
+	return calculateKindBreakdown(lock, sizes, installed), nil
+}
+
+// HasNonProd reports whether any peer, optional or dev dependencies are
+// installed.
+func (b kindBreakdown) HasNonProd() bool {
+	for kind, s := range b {
+		if kind != npm.DependencyKindProd && s.Packages > 0 {
+			return true
+		}
+	}
+
+	return false
+}
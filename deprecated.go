@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"package_size_calculator/pkg/npm"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// maxDeprecatedShown limits the deprecated packages listed per tree
+const maxDeprecatedShown = 10
+
+// findDeprecated checks the packages of the lockfile against their packuments,
+// which are cached by the client. Trees have hundreds of packages whose
+// packuments are mostly not cached, so this is only done with -deprecated.
+func findDeprecated(lock *npm.PackageLockJSON) []npm.DeprecatedPackage {
+	if !*fDeprecated {
+		return nil
+	}
+
+	deprecated, err := npmClient.FindDeprecated(lock)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to check some packages for deprecations")
+	}
+
+	return deprecated
+}
+
+// countOtherDeprecated counts the deprecated packages except the one at the
+// excluded path, like countOtherInstallScripts.
+func countOtherDeprecated(deprecated []npm.DeprecatedPackage, excluded string) uint64 {
+	var count uint64
+	for _, d := range deprecated {
+		if d.Path != excluded {
+			count++
+		}
+	}
+
+	return count
+}
+
+func reportDeprecated(w io.Writer, deprecated []npm.DeprecatedPackage, indent string) {
+	if len(deprecated) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "%s%s: %s\n", indent, bold.Sprint("Deprecated packages"), boldRed.Sprint(fmtInt(int64(len(deprecated)))))
+
+	for i, d := range deprecated {
+		if i == maxDeprecatedShown {
+			fmt.Fprintf(w, "%s  %s\n", indent, gray.Sprintf("... and %d more", len(deprecated)-maxDeprecatedShown))
+			break
+		}
+
+		// Messages sometimes span several lines
+		message := strings.Join(strings.Fields(d.Message), " ")
+		fmt.Fprintf(w, "%s  %s: %s\n", indent, boldYellow.Sprint(d.String()), gray.Sprint(message))
+	}
+}
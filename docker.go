@@ -29,7 +29,7 @@ func downloadBaseImage(c *docker_client.Client) error {
 	return jsonmessage.DisplayJSONMessagesStream(output, os.Stderr, termFd, isTerm, nil)
 }
 
-func installPackageInContainer(package_ npm.DependencyInfo) (internal.TmpDir, error) {
+func installPackageInContainer(package_ npm.DependencyInfo, opts installOptions) (internal.TmpDir, error) {
 	ctx := context.Background()
 
 	tmpDir, err := internal.NewTmpDir(fmt.Sprintf("package_size_%s_*", internal.SanetizeFileName(package_.String())))
@@ -38,7 +38,9 @@ func installPackageInContainer(package_ npm.DependencyInfo) (internal.TmpDir, er
 	}
 	log.Trace().Str("dir", tmpDir.String()).Msg("Created temp dir")
 
-	cmd := []string{"npm", "install", "--loglevel", "verbose", package_.String()}
+	cmd := []string{"npm", "install", "--loglevel", "verbose"}
+	cmd = append(cmd, opts.NPMArgs()...)
+	cmd = append(cmd, package_.String())
 
 	if err := runContainer(ctx, cmd, tmpDir); err != nil {
 		return tmpDir, err
@@ -47,19 +49,35 @@ func installPackageInContainer(package_ npm.DependencyInfo) (internal.TmpDir, er
 	return tmpDir, nil
 }
 
-func modifyPackage(p npm.PackageJSON, toAdd []npm.DependencyInfo, toRemove []npm.DependencyInfo) (internal.TmpDir, error) {
+func modifyPackage(p npm.PackageJSON, toAdd []npm.DependencyInfo, toRemove []npm.DependencyInfo, overrides []npm.Override) (internal.TmpDir, error) {
+	if *fInstaller == installerGo && len(overrides) > 0 {
+		return "", ErrOverridesUnsupported
+	}
+
+	p = p.Clone()
+
 	for _, dep := range toRemove {
-		if ok := p.Dependencies.Remove(dep); !ok {
+		if ok := p.RemoveDependency(dep); !ok {
 			log.Warn().Str("dependency", dep.String()).Msg("Dependency not found")
 		}
 	}
 
 	for _, dep := range toAdd {
-		if err := p.Dependencies.Add(dep); err != nil {
+		if err := p.AddDependency(dep); err != nil {
 			log.Error().Err(err).Str("dependency", dep.String()).Msg("Failed to add dependency")
 		}
 	}
 
+	for _, o := range overrides {
+		if err := p.AddOverride(o); err != nil {
+			log.Error().Err(err).Str("override", o.String()).Msg("Failed to add override")
+		}
+	}
+
+	// The lifecycle scripts of the package would run as the scripts of the root
+	// project, but the files they need aren't there
+	p.RemoveLifecycleScripts()
+
 	log.Debug().Msg("Modified package.json")
 
 	tmp, err := internal.NewTmpDir(fmt.Sprintf("package_size_%s_modified_*", internal.SanetizeFileName(p.Name)))
@@ -69,13 +87,24 @@ func modifyPackage(p npm.PackageJSON, toAdd []npm.DependencyInfo, toRemove []npm
 
 	path := filepath.Join(string(tmp), "package.json")
 
-	if err := internal.WriteJSONFile(path, p); err != nil {
+	content, err := p.JSON()
+	if err != nil {
+		return tmp, err
+	}
+
+	if err := os.WriteFile(path, content, 0644); err != nil {
 		return tmp, err
 	}
 
 	log.Debug().Str("path", path).Msg("Wrote modified package.json")
 
-	if err := runContainer(context.Background(), []string{"npm", "install", "--loglevel", "verbose"}, tmp); err != nil {
+	if *fInstaller == installerGo {
+		return tmp, installTreeInGo(p, tmp, targetPlatform)
+	}
+
+	// Consumers of the package don't install its dev dependencies
+	cmd := append([]string{"npm", "install", "--loglevel", "verbose", "--omit=dev"}, defaultInstallOptions().NPMArgs()...)
+	if err := runContainer(context.Background(), cmd, tmp); err != nil {
 		return tmp, err
 	}
 
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+)
+
+var ErrInvalidEnergyModel = errors.New("invalid energy model")
+
+// energyModel converts the saved traffic to energy and emissions. The traffic
+// is transferred over the network once and stored by every install.
+type energyModel struct {
+	// NetworkKWhPerGB is the energy of transferring a GB
+	NetworkKWhPerGB float64
+	// StorageKWhPerGBYear is the energy of storing a GB for a year
+	StorageKWhPerGBYear float64
+	// RetentionDays is how long an install is kept on average
+	RetentionDays float64
+	// GramsCO2ePerKWh is the carbon intensity of the electricity
+	GramsCO2ePerKWh float64
+}
+
+// defaultEnergyModel uses the network energy intensity and the global grid
+// intensity of the Sustainable Web Design model (v4) and the SSD storage
+// coefficient of Cloud Carbon Footprint (1.2 Wh per TB-hour). These are rough
+// averages, so the estimate is an order of magnitude at best.
+var defaultEnergyModel = energyModel{
+	NetworkKWhPerGB:     0.059,
+	StorageKWhPerGBYear: 0.0105,
+	RetentionDays:       30,
+	GramsCO2ePerKWh:     494,
+}
+
+// energyModelKeys are the coefficients that can be configured with -energy
+var energyModelKeys = map[string]func(m *energyModel) *float64{
+	"network":   func(m *energyModel) *float64 { return &m.NetworkKWhPerGB },
+	"storage":   func(m *energyModel) *float64 { return &m.StorageKWhPerGBYear },
+	"retention": func(m *energyModel) *float64 { return &m.RetentionDays },
+	"intensity": func(m *energyModel) *float64 { return &m.GramsCO2ePerKWh },
+}
+
+func (m energyModel) String() string {
+	return fmt.Sprintf(
+		"network %g kWh/GB, storage %g kWh/GB/year for %g days, %g gCO2e/kWh",
+		m.NetworkKWhPerGB,
+		m.StorageKWhPerGBYear,
+		m.RetentionDays,
+		m.GramsCO2ePerKWh,
+	)
+}
+
+// parseEnergyModel parses "default" or comma-separated key=value pairs that
+// override coefficients of the default model, e.g. "intensity=300". An empty
+// model disables energy estimates.
+func parseEnergyModel(s string) (*energyModel, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+
+	m := defaultEnergyModel
+	if s == "default" {
+		return &m, nil
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: \"%s\" isn't a key=value pair", ErrInvalidEnergyModel, pair)
+		}
+
+		field, ok := energyModelKeys[strings.ToLower(key)]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown coefficient \"%s\", expected network, storage, retention or intensity", ErrInvalidEnergyModel, key)
+		}
+
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("%w: \"%s\" isn't a non-negative number", ErrInvalidEnergyModel, value)
+		}
+
+		*field(&m) = v
+	}
+
+	return &m, nil
+}
+
+// energyEstimate is the energy and emissions a modification saves in a year.
+// All values are negative if the modification causes more traffic.
+type energyEstimate struct {
+	NetworkKWh float64
+	StorageKWh float64
+	KgCO2e     float64
+}
+
+func (e energyEstimate) TotalKWh() float64 {
+	return e.NetworkKWh + e.StorageKWh
+}
+
+// estimateEnergy estimates the energy of the yearly projection of the traffic.
+// It returns nil if the downloads are unknown.
+func estimateEnergy(t trafficChange, m *energyModel) *energyEstimate {
+	yearly, ok := yearlyProjection(t)
+	if !ok || m == nil {
+		return nil
+	}
+
+	gb := gigabytes(yearly.Saved)
+
+	e := &energyEstimate{
+		NetworkKWh: gb * m.NetworkKWhPerGB,
+		StorageKWh: gb * m.StorageKWhPerGBYear * m.RetentionDays / 365,
+	}
+	e.KgCO2e = e.TotalKWh() * m.GramsCO2ePerKWh / 1000
+
+	return e
+}
+
+// energyChange is the energy section of the report.
+type energyChange struct {
+	Model energyModel
+	// Current and AllVersions are nil if the downloads are unknown
+	Current *energyEstimate
+	// AllVersions is also nil with a version mix, where all versions save as
+	// much as the current version
+	AllVersions    *energyEstimate
+	WithVersionMix bool
+}
+
+func newEnergyChange(s statisticsChange, m *energyModel) *energyChange {
+	if m == nil {
+		return nil
+	}
+
+	e := &energyChange{
+		Model:          *m,
+		Current:        estimateEnergy(s.Traffic, m),
+		WithVersionMix: s.VersionMix != nil,
+	}
+	if !e.WithVersionMix {
+		e.AllVersions = estimateEnergy(s.TrafficAllVersions, m)
+	}
+
+	return e
+}
+
+// Headline is the estimate of all versions, or of the current version with a
+// version mix. It is nil if energy wasn't estimated or downloads are unknown.
+func (e *energyChange) Headline() *energyEstimate {
+	if e == nil {
+		return nil
+	}
+
+	if e.WithVersionMix {
+		return e.Current
+	}
+
+	return e.AllVersions
+}
+
+// rows are the labeled estimates shown in reports.
+func (e *energyChange) rows() []energyRow {
+	rows := []energyRow{{Label: "For current version", Estimate: e.Current}}
+	if !e.WithVersionMix {
+		rows = append(rows, energyRow{Label: "For all versions", Estimate: e.AllVersions})
+	}
+
+	return rows
+}
+
+type energyRow struct {
+	Label    string
+	Estimate *energyEstimate
+}
+
+// formattedEnergy formats the saved energy and emissions, e.g.
+// "1.2 MWh, 590 kg CO2e saved".
+func formattedEnergy(e *energyEstimate) string {
+	return fmt.Sprintf(
+		"%s, %s %s",
+		humanize.SIWithDigits(abs(e.TotalKWh())*1000, 2, "Wh"),
+		fmtCO2e(abs(e.KgCO2e)),
+		savedLabel(int(energySign(e)), "saved", "extra"),
+	)
+}
+
+// energySign is 1 if energy is saved and -1 if more is used.
+func energySign(e *energyEstimate) int64 {
+	if e.TotalKWh() > 0 {
+		return 1
+	} else if e.TotalKWh() < 0 {
+		return -1
+	}
+
+	return 0
+}
+
+// formattedEnergyBreakdown formats how much of the energy is spent on the
+// network and on storage.
+func formattedEnergyBreakdown(e *energyEstimate) string {
+	return fmt.Sprintf(
+		"network %s, storage %s",
+		humanize.SIWithDigits(abs(e.NetworkKWh)*1000, 2, "Wh"),
+		humanize.SIWithDigits(abs(e.StorageKWh)*1000, 2, "Wh"),
+	)
+}
+
+func fmtCO2e(kg float64) string {
+	switch {
+	case kg >= 1000:
+		return humanize.CommafWithDigits(kg/1000, 2) + " t CO2e"
+	case kg >= 1:
+		return humanize.CommafWithDigits(kg, 2) + " kg CO2e"
+	}
+
+	return humanize.CommafWithDigits(kg*1000, 2) + " g CO2e"
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"package_size_calculator/pkg/npm"
+
+	"github.com/dustin/go-humanize"
+	"github.com/rs/zerolog/log"
+)
+
+// sizeEstimate is the size of a package's tree calculated from the unpacked
+// sizes the registry reports, without installing it.
+type sizeEstimate struct {
+	Tree            *npm.ResolvedTree
+	Size            uint64
+	Files           uint64
+	Subdependencies uint64
+	// UnknownSizes is the number of packages without a reported size, which
+	// aren't included in Size
+	UnknownSizes int
+}
+
+func estimatePackageSize(pkg npm.PackageJSON) sizeEstimate {
+	log.Info().Str("package", pkg.String()).Str("platform", targetPlatform.String()).Msg("Resolving dependency tree for estimate")
+
+	tree := npmClient.ResolveTree(pkg, targetPlatform)
+	size, files, unknown := tree.UnpackedSize()
+
+	log.Info().Str("package", pkg.String()).Str("size", humanize.Bytes(size)).Msg("Estimated package size")
+
+	return sizeEstimate{
+		Tree:            tree,
+		Size:            size,
+		Files:           files,
+		Subdependencies: tree.Subdependencies(),
+		UnknownSizes:    unknown,
+	}
+}
+
+// estimatePackage estimates the size of a package without Docker, for quick
+// triage. The package is prompted for if the spec is empty, otherwise specs
+// without a version resolve to the latest version.
+func estimatePackage(input string) {
+	var packageInfo *npm.PackageInfo
+	var version *npm.PackageVersion
+
+	if input == "" {
+		packageInfo, version = promptPackageSpec(npmClient)
+		if version == nil {
+			v := packageInfo.Versions[promptPackageVersion(packageInfo, "Select version")]
+			version = &v
+		}
+	} else {
+		spec, err := npm.ParseSpec(input)
+		if err != nil {
+			log.Fatal().Err(err).Str("package", input).Msg("Failed to parse package spec")
+		}
+
+		packageInfo, version, err = npmClient.ResolveSpec(spec)
+		if err != nil {
+			log.Fatal().Err(err).Str("package", input).Msg("Failed to resolve package spec")
+		}
+	}
+
+	downloads, err := npmClient.GetPackageDownloadsLastWeek(packageInfo.Name)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to fetch package downloads")
+	}
+
+	estimate := estimatePackageSize(version.JSON)
+
+	var downloadsLastWeek *uint64
+	if dls, ok := downloads.ForVersion(version.Version.String()); ok {
+		downloadsLastWeek = &dls
+	}
+
+	s := stats{
+		TotalDownloads:    downloads.Total(),
+		DownloadsLastWeek: downloadsLastWeek,
+		Size:              estimate.Size,
+		Subdependencies:   estimate.Subdependencies,
+	}.Calculate()
+
+	err = writeTextReport(func(w io.Writer) { reportEstimate(w, version.JSON, s, estimate) })
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to write report")
+	}
+}
+
+func reportEstimate(w io.Writer, pkg npm.PackageJSON, s calculatedStats, estimate sizeEstimate) {
+	fmt.Fprintln(w)
+	boldGreen.Fprintln(w, "Package size estimate")
+	boldGreen.Fprintln(w, "=====================")
+	fmt.Fprintln(w, gray.Sprintf("Estimated from the unpacked sizes in the registry, the installed size may differ"))
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%s: %s\n", bold.Sprintf("Estimate for \"%s\"", boldYellow.Sprint(pkg.String())), humanize.Bytes(estimate.Size))
+	fmt.Fprintf(w, "  %s: %s\n", bold.Sprint("Files"), fmtInt(int64(estimate.Files)))
+	fmt.Fprintf(w, "  %s: %s\n", bold.Sprint("Platform"), estimate.Tree.Platform)
+	fmt.Fprintf(
+		w,
+		"  %s: %s %s\n",
+		bold.Sprint("Downloads last week"),
+		s.FormattedDownloadsLastWeek(),
+		grayParens("%s%%", s.FormattedPercentDownloadsOfVersion()),
+	)
+	fmt.Fprintf(w, "  %s: %s\n", bold.Sprint("Estimated traffic last week"), s.FormattedTrafficLastWeek())
+	fmt.Fprintf(w, "  %s: %s\n", bold.Sprint("Subdependencies"), s.FormattedSubdependencies())
+
+	if estimate.UnknownSizes > 0 {
+		fmt.Fprintln(w)
+		boldYellow.Fprintf(w, "%s without a size in the registry, they aren't included\n", pluralize(estimate.UnknownSizes, "package", "packages"))
+	}
+
+	if len(estimate.Tree.Unresolved) > 0 {
+		fmt.Fprintln(w)
+		boldYellow.Fprintln(w, "Dependencies that couldn't be resolved:")
+		for _, d := range estimate.Tree.Unresolved {
+			fmt.Fprintf(w, "  %s %s\n", d, grayParens("%s", d.Err))
+		}
+	}
+}
+
+// reportEstimateAccuracy compares an estimate to the measured size of the
+// installed package.
+func reportEstimateAccuracy(w io.Writer, estimate estimateSummary) {
+	fmt.Fprintf(
+		w,
+		"  %s: %s %s\n",
+		bold.Sprint("Estimated size without installing"),
+		humanize.Bytes(estimate.Size),
+		grayParens(
+			"%s%% of the measured size, %s subdependencies",
+			fmtPercent(estimate.PercentOfMeasured),
+			fmtInt(int64(estimate.Subdependencies)),
+		),
+	)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"package_size_calculator/pkg/npm"
+	"strings"
+	"testing"
+)
+
+func TestEstimatePackageSize(t *testing.T) {
+	packuments := map[string]string{
+		"b": `{"name": "b", "dist-tags": {"latest": "1.0.0"}, "versions": {
+			"1.0.0": {"name": "b", "version": "1.0.0", "dependencies": {"c": "^1.0.0"}, "dist": {"unpackedSize": 200, "fileCount": 2}}
+		}}`,
+		// Old npm versions didn't report sizes
+		"c": `{"name": "c", "dist-tags": {"latest": "1.0.0"}, "versions": {
+			"1.0.0": {"name": "c", "version": "1.0.0", "dist": {}}
+		}}`,
+	}
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		packument, ok := packuments[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		fmt.Fprint(w, packument)
+	}))
+	defer s.Close()
+
+	prevClient := npmClient
+	npmClient = npm.New(npm.WithBaseURLs(s.URL, s.URL))
+	defer func() { npmClient = prevClient }()
+
+	var pkg npm.PackageJSON
+	if err := json.Unmarshal([]byte(`{"name": "a", "version": "1.0.0", "dependencies": {"b": "^1.0.0", "c": "^1.0.0"}, "dist": {"unpackedSize": 1000, "fileCount": 10}}`), &pkg); err != nil {
+		t.Fatal(err)
+	}
+
+	e := estimatePackageSize(pkg)
+	if e.Size != 1200 || e.Files != 12 || e.Subdependencies != 2 || e.UnknownSizes != 1 {
+		t.Errorf("estimate = %d bytes, %d files, %d subdependencies, %d unknown, want 1200 bytes, 12 files, 2 subdependencies, 1 unknown", e.Size, e.Files, e.Subdependencies, e.UnknownSizes)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"package_size_calculator/pkg/npm"
+)
+
+type exclusiveStats struct {
+	Size uint64
+	// Packages is the number of packages that would be removed, including the
+	// removed dependencies themselves
+	Packages uint64
+}
+
+// calculateExclusiveStats calculates what removing the given dependencies from
+// the installed package would remove from its tree, without reinstalling it.
+// Packages that are also required by other parts of the tree stay, so shared
+// subtrees aren't counted as savings.
+func calculateExclusiveStats(graph *npm.Graph, sizes map[string]uint64, installed string, removed []npm.DependencyInfo) exclusiveStats {
+	installedNode, ok := graph.Nodes[installed]
+	if !ok {
+		return exclusiveStats{}
+	}
+
+	removedNames := make(map[string]struct{}, len(removed))
+	for _, d := range removed {
+		removedNames[d.Name] = struct{}{}
+	}
+
+	before := graph.Reachable([]*npm.GraphNode{graph.Root}, npm.AllEdges)
+	after := graph.Reachable([]*npm.GraphNode{graph.Root}, func(edge *npm.GraphEdge) bool {
+		if edge.From != installedNode {
+			return true
+		}
+
+		_, ok := removedNames[edge.Name]
+		return !ok
+	})
+
+	var s exclusiveStats
+	for n := range before {
+		if after.Has(n) {
+			continue
+		}
+
+		s.Packages++
+		s.Size += sizes[n.Path]
+	}
+
+	return s
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"os"
+	"package_size_calculator/pkg/npm"
+	"path/filepath"
+	"testing"
+)
+
+func TestCalculateExclusiveStats(t *testing.T) {
+	// The sizes are powers of ten, so the size shows which packages are
+	// counted
+	sizes := map[string]uint64{"pkg": 10000, "a": 1, "b": 10, "c": 100, "d": 1000}
+
+	tests := []struct {
+		name string
+		// packages are the lockfile entries, the project depends on the
+		// package pkg that the dependencies are removed from
+		packages string
+		removed  []string
+		want     exclusiveStats
+	}{
+		{
+			name: "exclusive subtree",
+			packages: `
+				"": {"name": "project", "dependencies": {"pkg": "^1.0.0"}},
+				"node_modules/pkg": {"version": "1.0.0", "dependencies": {"a": "^1.0.0"}},
+				"node_modules/a": {"version": "1.0.0", "dependencies": {"c": "^1.0.0"}},
+				"node_modules/c": {"version": "1.0.0"}`,
+			removed: []string{"a"},
+			want:    exclusiveStats{Size: 101, Packages: 2},
+		},
+		{
+			name: "shared with a sibling",
+			packages: `
+				"": {"name": "project", "dependencies": {"pkg": "^1.0.0"}},
+				"node_modules/pkg": {"version": "1.0.0", "dependencies": {"a": "^1.0.0", "b": "^1.0.0"}},
+				"node_modules/a": {"version": "1.0.0", "dependencies": {"c": "^1.0.0"}},
+				"node_modules/b": {"version": "1.0.0", "dependencies": {"c": "^1.0.0"}},
+				"node_modules/c": {"version": "1.0.0"}`,
+			removed: []string{"a"},
+			want:    exclusiveStats{Size: 1, Packages: 1},
+		},
+		{
+			name: "shared with the project",
+			packages: `
+				"": {"name": "project", "dependencies": {"pkg": "^1.0.0", "c": "^1.0.0"}},
+				"node_modules/pkg": {"version": "1.0.0", "dependencies": {"a": "^1.0.0"}},
+				"node_modules/a": {"version": "1.0.0", "dependencies": {"c": "^1.0.0"}},
+				"node_modules/c": {"version": "1.0.0"}`,
+			removed: []string{"a", "c"},
+			want:    exclusiveStats{Size: 1, Packages: 1},
+		},
+		{
+			name: "diamond",
+			packages: `
+				"": {"name": "project", "dependencies": {"pkg": "^1.0.0"}},
+				"node_modules/pkg": {"version": "1.0.0", "dependencies": {"a": "^1.0.0", "b": "^1.0.0"}},
+				"node_modules/a": {"version": "1.0.0", "dependencies": {"d": "^1.0.0"}},
+				"node_modules/b": {"version": "1.0.0", "dependencies": {"d": "^1.0.0"}},
+				"node_modules/d": {"version": "1.0.0"}`,
+			removed: []string{"a", "b"},
+			want:    exclusiveStats{Size: 1011, Packages: 3},
+		},
+		{
+			name: "half of a diamond",
+			packages: `
+				"": {"name": "project", "dependencies": {"pkg": "^1.0.0"}},
+				"node_modules/pkg": {"version": "1.0.0", "dependencies": {"a": "^1.0.0", "b": "^1.0.0"}},
+				"node_modules/a": {"version": "1.0.0", "dependencies": {"d": "^1.0.0"}},
+				"node_modules/b": {"version": "1.0.0", "dependencies": {"d": "^1.0.0"}},
+				"node_modules/d": {"version": "1.0.0"}`,
+			removed: []string{"b"},
+			want:    exclusiveStats{Size: 10, Packages: 1},
+		},
+		{
+			name: "cycle",
+			packages: `
+				"": {"name": "project", "dependencies": {"pkg": "^1.0.0"}},
+				"node_modules/pkg": {"version": "1.0.0", "dependencies": {"a": "^1.0.0"}},
+				"node_modules/a": {"version": "1.0.0", "dependencies": {"b": "^1.0.0"}},
+				"node_modules/b": {"version": "1.0.0", "dependencies": {"a": "^1.0.0"}}`,
+			removed: []string{"a"},
+			want:    exclusiveStats{Size: 11, Packages: 2},
+		},
+		{
+			name: "cycle through the package",
+			packages: `
+				"": {"name": "project", "dependencies": {"pkg": "^1.0.0"}},
+				"node_modules/pkg": {"version": "1.0.0", "dependencies": {"a": "^1.0.0"}},
+				"node_modules/a": {"version": "1.0.0", "peerDependencies": {"pkg": "^1.0.0"}}`,
+			removed: []string{"a"},
+			want:    exclusiveStats{Size: 1, Packages: 1},
+		},
+		{
+			name: "not a dependency of the package",
+			packages: `
+				"": {"name": "project", "dependencies": {"pkg": "^1.0.0", "c": "^1.0.0"}},
+				"node_modules/pkg": {"version": "1.0.0"},
+				"node_modules/c": {"version": "1.0.0"}`,
+			removed: []string{"c"},
+			want:    exclusiveStats{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lockfile := `{"lockfileVersion": 3, "packages": {` + test.packages + `}}`
+
+			path := filepath.Join(t.TempDir(), "package-lock.json")
+			if err := os.WriteFile(path, []byte(lockfile), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			lock, err := npm.ParsePackageLockJSON(path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			removed := make([]npm.DependencyInfo, len(test.removed))
+			for i, name := range test.removed {
+				removed[i] = npm.DependencyInfo{Name: name}
+			}
+
+			got := calculateExclusiveStats(npm.NewGraph(lock), sizes, "pkg", removed)
+			if got != test.want {
+				t.Errorf("exclusive stats = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"package_size_calculator/pkg/npm"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	historyReleasesAll   = "all"
+	historyReleasesMinor = "minor"
+	historyReleasesMajor = "major"
+
+	// maxParallelHistoryInstalls limits the installs running at once, since
+	// a history can have hundreds of versions
+	maxParallelHistoryInstalls = 4
+
+	historyChartWidth  = 60
+	historyChartHeight = 8
+)
+
+// historyBlocks are the eighths of a chart cell from empty to full
+var historyBlocks = []string{" ", "▁", "▂", "▃", "▄", "▅", "▆", "▇", "█"}
+
+// historyMeasurement is the size of a version, as cached between runs.
+type historyMeasurement struct {
+	Version         string
+	ReleaseTime     time.Time
+	Size            uint64
+	Subdependencies uint64
+	MeasuredAt      time.Time
+	// Cached is true if the measurement is from an earlier run
+	Cached bool  `json:"-"`
+	Err    error `json:"-"`
+}
+
+// packageHistory measures how the size of a package changed over its
+// releases. Specs with a range limit the measured versions to the range.
+func packageHistory(input string) {
+	spec, err := npm.ParseSpec(input)
+	if err != nil {
+		log.Fatal().Err(err).Str("package", input).Msg("Failed to parse package spec")
+	}
+
+	info, err := npmClient.GetPackageInfo(spec.RegistryName())
+	if err != nil {
+		log.Fatal().Err(err).Str("package", input).Msg("Failed to fetch package info")
+	}
+
+	releases := *fHistoryReleases
+	if releases == "" {
+		releases = historyReleasesMinor
+		if spec.Target().Type == npm.SpecTypeRange {
+			releases = historyReleasesAll
+		}
+	}
+
+	versions, err := selectHistoryVersions(info, spec, releases)
+	if err != nil {
+		log.Fatal().Err(err).Str("package", input).Msg("Failed to select versions")
+	}
+
+	log.Info().Str("package", info.Name).Str("releases", releases).Int("versions", len(versions)).Msg("Measuring size history")
+
+	cachePath := historyCachePath()
+	cache := loadHistoryCache(cachePath)
+
+	measurements := make([]historyMeasurement, len(versions))
+	semaphore := make(chan struct{}, maxParallelHistoryInstalls)
+	wg := sync.WaitGroup{}
+	for i, v := range versions {
+		key := historyCacheKey(info.Name, v.Version.String())
+		if cached, ok := cache[key]; ok {
+			cached.Cached = true
+			measurements[i] = cached
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			measurements[i] = measureHistoryVersion(v)
+		}()
+	}
+	wg.Wait()
+
+	for _, m := range measurements {
+		if m.Err == nil && !m.Cached {
+			cache[historyCacheKey(info.Name, m.Version)] = m
+		}
+	}
+	if err := saveHistoryCache(cachePath, cache); err != nil {
+		log.Error().Err(err).Str("path", cachePath).Msg("Failed to save history cache")
+	}
+
+	slices.SortStableFunc(measurements, func(a, b historyMeasurement) int {
+		return a.ReleaseTime.Compare(b.ReleaseTime)
+	})
+
+	err = writeTextReport(func(w io.Writer) { reportHistory(w, info.Name, releases, measurements) })
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to write report")
+	}
+}
+
+// selectHistoryVersions picks the stable versions to measure: all of them,
+// or only the last release of each minor or major version.
+func selectHistoryVersions(info *npm.PackageInfo, spec npm.Spec, releases string) ([]npm.PackageVersion, error) {
+	if releases != historyReleasesAll && releases != historyReleasesMinor && releases != historyReleasesMajor {
+		return nil, errors.Errorf("unknown releases \"%s\", expected all, minor or major", releases)
+	}
+
+	filtered := spec.Target().Type == npm.SpecTypeRange
+
+	var versions []npm.PackageVersion
+	seen := map[string]bool{}
+
+	// Sorted starts with the newest version, so the first version of each
+	// minor or major is its last release
+	for _, v := range info.Versions.Sorted() {
+		if v.IsPreRelease() || (filtered && !spec.Matches(info, v)) {
+			continue
+		}
+
+		group := v.String()
+		switch releases {
+		case historyReleasesMinor:
+			group = fmt.Sprintf("%v.%v", v.Major(), v.Minor())
+		case historyReleasesMajor:
+			group = fmt.Sprint(v.Major())
+		}
+		if seen[group] {
+			continue
+		}
+		seen[group] = true
+
+		versions = append(versions, info.Versions[v.String()])
+	}
+
+	if len(versions) == 0 {
+		return nil, errors.Errorf("no stable versions of %s match \"%s\"", info.Name, spec.RawSpec)
+	}
+
+	return versions, nil
+}
+
+func measureHistoryVersion(v npm.PackageVersion) historyMeasurement {
+	m := historyMeasurement{
+		Version:     v.Version.String(),
+		ReleaseTime: v.ReleaseTime,
+		MeasuredAt:  time.Now(),
+	}
+
+	size, tmpDir, err := measurePackageSize(v.JSON.AsDependency())
+	if tmpDir != "" && !*fNoCleanup {
+		defer tmpDir.Remove()
+	}
+	if err != nil {
+		m.Err = err
+		return m
+	}
+	m.Size = size
+
+	lock, err := npm.ParsePackageLockJSON(tmpDir.Join("package-lock.json"))
+	if err != nil {
+		m.Err = errors.Wrap(err, "failed to parse package-lock.json")
+		return m
+	}
+	m.Subdependencies = getSubdependenciesCount(lock)
+
+	log.Info().Str("package", v.JSON.String()).Str("size", humanize.Bytes(m.Size)).Msg("Measured version")
+
+	return m
+}
+
+// historyCachePath is the file given with -history-cache, or a file in the
+// user's cache directory.
+func historyCachePath() string {
+	if *fHistoryCache != "" {
+		return *fHistoryCache
+	}
+
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to find cache directory, measurements won't be cached")
+		return ""
+	}
+
+	return filepath.Join(dir, "package-size-calculator", "history.json")
+}
+
+// historyCacheKey identifies a measurement. Sizes depend on the platform and
+// on whether install scripts ran, so both are part of the key.
+func historyCacheKey(name, version string) string {
+	return fmt.Sprintf("%s@%s %s %s", name, version, targetPlatform, *fInstaller)
+}
+
+func loadHistoryCache(path string) map[string]historyMeasurement {
+	cache := map[string]historyMeasurement{}
+	if path == "" {
+		return cache
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warn().Err(err).Str("path", path).Msg("Failed to read history cache")
+		}
+
+		return cache
+	}
+
+	if err := json.Unmarshal(data, &cache); err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("Ignoring corrupted history cache")
+		return map[string]historyMeasurement{}
+	}
+
+	log.Debug().Str("path", path).Int("measurements", len(cache)).Msg("Loaded history cache")
+
+	return cache
+}
+
+func saveHistoryCache(path string, cache map[string]historyMeasurement) error {
+	if path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode history cache")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.Wrap(err, "failed to create cache directory")
+	}
+
+	return errors.Wrap(os.WriteFile(path, data, 0644), "failed to write history cache")
+}
+
+func reportHistory(w io.Writer, name string, releases string, measurements []historyMeasurement) {
+	fmt.Fprintln(w)
+	boldGreen.Fprintln(w, "Size history report")
+	boldGreen.Fprintln(w, "===================")
+
+	description := "every release"
+	switch releases {
+	case historyReleasesMinor:
+		description = "last release of each minor version"
+	case historyReleasesMajor:
+		description = "last release of each major version"
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%s %s\n", bold.Sprintf("Size history of \"%s\"", boldYellow.Sprint(name)), grayParens("%s", description))
+	fmt.Fprintln(w)
+
+	var measured []historyMeasurement
+
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, "  Version\tReleased\tSize\tSubdependencies\tChange")
+	for _, m := range measurements {
+		if m.Err != nil {
+			// Colors would break the alignment of the size column
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t\t%s\n", m.Version, m.ReleaseTime.Format(time.DateOnly), "failed", boldRed.Sprint(m.Err))
+			continue
+		}
+
+		change := ""
+		if len(measured) > 0 {
+			previous := measured[len(measured)-1]
+			change = fmtSignedBytes(int64(m.Size) - int64(previous.Size))
+		}
+		if m.Cached {
+			change += " " + grayParens("cached")
+		}
+
+		fmt.Fprintf(
+			tw,
+			"  %s\t%s\t%s\t%s\t%s\n",
+			m.Version,
+			m.ReleaseTime.Format(time.DateOnly),
+			humanize.Bytes(m.Size),
+			fmtInt(int64(m.Subdependencies)),
+			strings.TrimSpace(change),
+		)
+
+		measured = append(measured, m)
+	}
+	tw.Flush()
+
+	if len(measured) < 2 {
+		return
+	}
+
+	fmt.Fprintln(w)
+	renderHistoryChart(w, "Install size", measured, func(m historyMeasurement) uint64 { return m.Size }, func(v uint64) string { return humanize.Bytes(v) })
+	fmt.Fprintln(w)
+	renderHistoryChart(w, "Subdependencies", measured, func(m historyMeasurement) uint64 { return m.Subdependencies }, func(v uint64) string { return fmtInt(int64(v)) })
+}
+
+// renderHistoryChart draws the values over the release time as a step chart,
+// each column shows the latest version released until then.
+func renderHistoryChart(w io.Writer, title string, measurements []historyMeasurement, value func(historyMeasurement) uint64, format func(uint64) string) {
+	first := measurements[0].ReleaseTime
+	span := measurements[len(measurements)-1].ReleaseTime.Sub(first)
+
+	maxValue := slices.MaxFunc(measurements, func(a, b historyMeasurement) int {
+		return cmp.Compare(value(a), value(b))
+	})
+	top := value(maxValue)
+
+	// The eighths of the chart's height that each column fills
+	levels := make([]int, historyChartWidth)
+	next := 0
+	var current uint64
+	for column := range levels {
+		until := first.Add(time.Duration(float64(span) * float64(column) / float64(historyChartWidth-1)))
+		for next < len(measurements) && !measurements[next].ReleaseTime.After(until) {
+			current = value(measurements[next])
+			next++
+		}
+
+		if top > 0 {
+			levels[column] = int(float64(current) / float64(top) * historyChartHeight * 8)
+		}
+	}
+
+	labels := []string{format(top), format(0)}
+	labelWidth := max(len(labels[0]), len(labels[1]))
+
+	bold.Fprintf(w, "  %s:\n", title)
+	for row := historyChartHeight - 1; row >= 0; row-- {
+		label := ""
+		switch row {
+		case historyChartHeight - 1:
+			label = labels[0]
+		case 0:
+			label = labels[1]
+		}
+
+		var line strings.Builder
+		for _, level := range levels {
+			line.WriteString(historyBlocks[min(max(level-row*8, 0), 8)])
+		}
+
+		fmt.Fprintf(w, "  %*s %s%s\n", labelWidth, label, gray.Sprint("│"), boldYellow.Sprint(line.String()))
+	}
+
+	fmt.Fprintf(w, "  %*s %s\n", labelWidth, "", gray.Sprint("└"+strings.Repeat("─", historyChartWidth)))
+
+	start := first.Format(time.DateOnly)
+	end := measurements[len(measurements)-1].ReleaseTime.Format(time.DateOnly)
+	fmt.Fprintf(w, "  %*s  %s%*s\n", labelWidth, "", start, historyChartWidth-len(start), end)
+}
@@ -0,0 +1,387 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"package_size_calculator/internal"
+	"package_size_calculator/internal/build"
+	"package_size_calculator/pkg/time_helpers"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/rs/zerolog/log"
+)
+
+//go:embed templates/report.html
+var htmlReportTemplateSource string
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(htmlReportTemplateSource))
+
+// htmlRenderer writes a standalone HTML page with the report and a treemap of
+// the installed trees.
+type htmlRenderer struct{}
+
+func (htmlRenderer) Render(w io.Writer, r *Report) error {
+	return htmlReportTemplate.Execute(w, newHTMLReport(r))
+}
+
+// htmlReport is the data of the HTML template.
+type htmlReport struct {
+	Title       string
+	GeneratedAt time.Time
+	Version     string
+	Sections    []htmlSection
+	Views       []treeView
+}
+
+type htmlSection struct {
+	Title string
+	Rows  []htmlRow
+}
+
+type htmlRow struct {
+	Label string
+	Value string
+	// Class is "better", "worse" or "flagged" to highlight the value
+	Class string
+}
+
+func newHTMLReport(r *Report) *htmlReport {
+	h := &htmlReport{
+		GeneratedAt: r.GeneratedAt,
+		Version:     build.Version,
+		Views:       r.Trees,
+	}
+
+	switch {
+	case len(r.Packages) == 1:
+		h.Title = fmt.Sprintf("Package size report for %s", r.Packages[0].Name)
+		h.Sections = append(h.Sections, htmlPackageSection("Package info", r, r.Packages[0]))
+	case len(r.Packages) == 2:
+		h.Title = fmt.Sprintf("Package size report for %s → %s", r.Packages[0].Name, r.Packages[1].Name)
+		h.Sections = append(h.Sections,
+			htmlPackageSection("Old version", r, r.Packages[0]),
+			htmlPackageSection("New version", r, r.Packages[1]),
+		)
+	}
+
+	if len(r.Removed) > 0 {
+		s := htmlSection{Title: "Removed dependencies"}
+		for _, d := range r.Removed {
+			exclusive := "N/A"
+			if d.Exclusive != nil {
+				exclusive = fmt.Sprintf("%s exclusive, %s packages", humanize.Bytes(d.Exclusive.Size), fmtInt(int64(d.Exclusive.Packages)))
+			}
+
+			s.Rows = append(s.Rows, htmlRow{
+				Label: d.Name,
+				Value: fmt.Sprintf(
+					"%s (%s%%, %s), %s subdependencies, %s downloads last week",
+					humanize.Bytes(d.Stats.Size),
+					fmtPercent(d.PercentOfSize),
+					exclusive,
+					d.Stats.FormattedSubdependencies(),
+					d.Stats.FormattedDownloadsLastWeek(),
+				),
+				Class: "better",
+			})
+		}
+
+		if r.ExclusiveOfRemoved != nil {
+			s.Rows = append(s.Rows, htmlRow{
+				Label: "All removed dependencies",
+				Value: fmt.Sprintf(
+					"%s exclusive (%s%%, %s packages)",
+					humanize.Bytes(r.ExclusiveOfRemoved.Size),
+					fmtPercent(r.ExclusiveOfRemoved.PercentOfSize),
+					fmtInt(int64(r.ExclusiveOfRemoved.Packages)),
+				),
+				Class: "better",
+			})
+		}
+
+		h.Sections = append(h.Sections, s)
+	}
+
+	if len(r.Added) > 0 {
+		s := htmlSection{Title: "Added dependencies"}
+		for _, d := range r.Added {
+			s.Rows = append(s.Rows, htmlRow{
+				Label: d.Name,
+				Value: fmt.Sprintf(
+					"%s (%s%%), %s subdependencies, %s downloads last week",
+					humanize.Bytes(d.Stats.Size),
+					fmtPercent(d.PercentOfSize),
+					d.Stats.FormattedSubdependencies(),
+					d.Stats.FormattedDownloadsLastWeek(),
+				),
+				Class: "worse",
+			})
+		}
+
+		h.Sections = append(h.Sections, s)
+	}
+
+	if len(r.Overrides) > 0 {
+		s := htmlSection{Title: "Overrides"}
+		for _, o := range r.Overrides {
+			s.Rows = append(s.Rows, htmlRow{
+				Label: o.Name,
+				Value: fmt.Sprintf("%s, %+d subdependencies", htmlSignedBytes(o.SizeDelta), o.SubdependenciesDelta),
+				Class: htmlDeltaClass(o.SizeDelta),
+			})
+		}
+
+		h.Sections = append(h.Sections, s)
+	}
+
+	h.Sections = append(h.Sections, htmlStatisticsSection(r))
+
+	if r.Statistics.VersionMix != nil {
+		h.Sections = append(h.Sections, htmlVersionMixSection(r.Statistics.VersionMix))
+	}
+
+	if r.Energy != nil {
+		h.Sections = append(h.Sections, htmlEnergySection(r.Energy))
+	}
+
+	return h
+}
+
+func htmlPackageSection(title string, r *Report, p packageSummary) htmlSection {
+	s := htmlSection{Title: title}
+
+	s.Rows = append(s.Rows,
+		htmlRow{Label: "Package", Value: p.Name},
+		htmlRow{Label: "Size", Value: humanize.Bytes(p.Stats.Size)},
+		htmlRow{
+			Label: "Released",
+			Value: fmt.Sprintf("%s (%s ago)", p.ReleaseTime.Format(time.DateOnly), time_helpers.FormatDuration(r.GeneratedAt.Sub(p.ReleaseTime))),
+		},
+		htmlRow{
+			Label: "Downloads last week",
+			Value: fmt.Sprintf("%s (%s%%)", p.Stats.FormattedDownloadsLastWeek(), p.Stats.FormattedPercentDownloadsOfVersion()),
+		},
+		htmlRow{Label: "Estimated traffic last week", Value: p.Stats.FormattedTrafficLastWeek()},
+	)
+
+	if p.Estimate != nil {
+		s.Rows = append(s.Rows, htmlRow{
+			Label: "Estimated from registry",
+			Value: fmt.Sprintf("%s (%s%% of measured)", humanize.Bytes(p.Estimate.Size), fmtPercent(p.Estimate.PercentOfMeasured)),
+		})
+	}
+
+	s.Rows = append(s.Rows, htmlRow{Label: "Subdependencies", Value: p.Stats.FormattedSubdependencies()})
+	for _, k := range p.Kinds {
+		s.Rows = append(s.Rows, htmlRow{
+			Label: kindLabel(k.Kind),
+			Value: fmt.Sprintf("%s (%s)", fmtInt(int64(k.Packages)), humanize.Bytes(k.Size)),
+		})
+	}
+
+	if len(p.InstallScripts) > 0 {
+		s.Rows = append(s.Rows, htmlRow{Label: "Install scripts", Value: pluralize(len(p.InstallScripts), "package", "packages"), Class: "flagged"})
+	}
+	if len(p.Deprecated) > 0 {
+		s.Rows = append(s.Rows, htmlRow{Label: "Deprecated", Value: pluralize(len(p.Deprecated), "package", "packages"), Class: "flagged"})
+	}
+
+	for _, l := range p.Licenses {
+		row := htmlRow{Label: "License " + string(l.License), Value: pluralize(len(l.Packages), "package", "packages")}
+		if l.Status != licenseOK {
+			row.Value += fmt.Sprintf(" (%s: %s)", l.Status, formatLicensePackages(l.Packages))
+			row.Class = "flagged"
+		}
+
+		s.Rows = append(s.Rows, row)
+	}
+
+	if p.LatestVersion != nil {
+		s.Rows = append(s.Rows, htmlRow{
+			Label: "Latest version",
+			Value: fmt.Sprintf("%s (%s ago)", p.LatestVersion.Version, time_helpers.FormatDuration(r.GeneratedAt.Sub(p.LatestVersion.ReleaseTime))),
+		})
+	}
+
+	return s
+}
+
+func htmlStatisticsSection(r *Report) htmlSection {
+	st := r.Statistics
+
+	s := htmlSection{
+		Title: "Estimated new statistics",
+		Rows: []htmlRow{
+			{
+				Label: "Package size",
+				Value: fmt.Sprintf("%s → %s (%s%%)", humanize.Bytes(st.OldSize), humanize.Bytes(st.NewSize), fmtPercent(st.PercentSize)),
+				Class: htmlDeltaClass(int64(st.NewSize) - int64(st.OldSize)),
+			},
+			{
+				Label: "Subdependencies",
+				Value: fmt.Sprintf("%s → %s", fmtInt(int64(st.OldSubdependencies)), fmtInt(int64(st.NewSubdependencies))),
+				Class: htmlDeltaClass(int64(st.NewSubdependencies) - int64(st.OldSubdependencies)),
+			},
+		},
+	}
+
+	s.Rows = append(s.Rows, htmlTrafficRows("Traffic for current version", st.Traffic, r.EgressPrice)...)
+	if st.VersionMix == nil {
+		s.Rows = append(s.Rows, htmlTrafficRows("Traffic for all versions", st.TrafficAllVersions, r.EgressPrice)...)
+	} else if st.TrafficAllVersions.Old != nil {
+		// Only the measured version changes in a mix, so its savings would be
+		// the same as those of the current version
+		s.Rows = append(s.Rows, htmlRow{
+			Label: "Traffic for version mix",
+			Value: fmt.Sprintf("%s → %s", humanize.BigBytes(st.TrafficAllVersions.Old), humanize.BigBytes(st.TrafficAllVersions.New)),
+		})
+	}
+
+	for _, k := range r.Kinds {
+		s.Rows = append(s.Rows, htmlRow{
+			Label: kindLabel(k.Kind),
+			Value: fmt.Sprintf("%s → %s (%s → %s)", fmtInt(int64(k.Old.Packages)), fmtInt(int64(k.New.Packages)), humanize.Bytes(k.Old.Size), humanize.Bytes(k.New.Size)),
+			Class: htmlDeltaClass(int64(k.New.Size) - int64(k.Old.Size)),
+		})
+	}
+
+	for _, c := range r.Counts {
+		s.Rows = append(s.Rows, htmlRow{
+			Label: c.Label,
+			Value: fmt.Sprintf("%s → %s", fmtInt(int64(c.Old)), fmtInt(int64(c.New))),
+			Class: htmlDeltaClass(int64(c.New) - int64(c.Old)),
+		})
+	}
+
+	for _, l := range r.Licenses {
+		row := htmlRow{Label: "Removed license " + string(l.License), Value: formatLicensePackages(l.Packages)}
+		if l.Added {
+			row.Label = "Added license " + string(l.License)
+			if l.Status != licenseOK {
+				row.Value = fmt.Sprintf("%s (%s)", row.Value, l.Status)
+				row.Class = "flagged"
+			}
+		}
+
+		s.Rows = append(s.Rows, row)
+	}
+
+	return s
+}
+
+func htmlTrafficRows(label string, t trafficChange, price *egressPrice) []htmlRow {
+	if t.Old == nil || t.New == nil {
+		return []htmlRow{{Label: label, Value: "N/A"}}
+	}
+
+	saved := t.Saved()
+	class := htmlDeltaClass(-int64(saved.Sign()))
+
+	change := "no change"
+	if saved.Sign() > 0 {
+		change = humanize.BigBytes(saved) + " saved"
+	} else if saved.Sign() < 0 {
+		change = humanize.BigBytes(saved.Neg(saved)) + " wasted"
+	}
+
+	rows := []htmlRow{{
+		Label: label,
+		Value: fmt.Sprintf("%s → %s (%s)", humanize.BigBytes(t.Old), humanize.BigBytes(t.New), change),
+		Class: class,
+	}}
+
+	volume, cost := formattedProjections(t.Projections)
+	if volume != "" {
+		rows = append(rows, htmlRow{Label: "Projected", Value: volume, Class: class})
+	}
+	if cost != "" {
+		rows = append(rows, htmlRow{Label: "Egress cost", Value: fmt.Sprintf("%s (%s)", cost, price), Class: class})
+	}
+
+	return rows
+}
+
+func htmlVersionMixSection(mix *versionMix) htmlSection {
+	s := htmlSection{Title: "Version mix of last week's downloads"}
+
+	for _, v := range mix.Versions {
+		value := fmt.Sprintf("%s%% of downloads, %s, %s traffic", fmtPercent(v.Share), humanize.Bytes(v.Size), humanize.BigBytes(v.Traffic))
+		if v.Estimated {
+			value += " (estimated)"
+		}
+
+		s.Rows = append(s.Rows, htmlRow{Label: v.Version, Value: value})
+	}
+
+	if mix.OtherDownloads > 0 {
+		s.Rows = append(s.Rows, htmlRow{
+			Label: "Other versions",
+			Value: fmt.Sprintf(
+				"%s%% of downloads, %s (average size)",
+				fmtPercent(calculatePercentage(float64(mix.OtherDownloads), float64(mix.TotalDownloads))),
+				humanize.Bytes(mix.OtherSize),
+			),
+		})
+	}
+
+	return s
+}
+
+func htmlEnergySection(e *energyChange) htmlSection {
+	s := htmlSection{Title: "Estimated energy and emissions per year"}
+
+	for _, row := range e.rows() {
+		if row.Estimate == nil {
+			s.Rows = append(s.Rows, htmlRow{Label: row.Label, Value: "N/A"})
+			continue
+		}
+
+		s.Rows = append(s.Rows, htmlRow{
+			Label: row.Label,
+			Value: fmt.Sprintf("%s (%s)", formattedEnergy(row.Estimate), formattedEnergyBreakdown(row.Estimate)),
+			Class: htmlDeltaClass(-energySign(row.Estimate)),
+		})
+	}
+
+	s.Rows = append(s.Rows, htmlRow{Label: "Model", Value: e.Model.String()})
+
+	return s
+}
+
+// htmlDeltaClass highlights decreases as better and increases as worse.
+func htmlDeltaClass(delta int64) string {
+	if delta < 0 {
+		return "better"
+	} else if delta > 0 {
+		return "worse"
+	}
+
+	return ""
+}
+
+func htmlSignedBytes(delta int64) string {
+	if delta < 0 {
+		return "-" + humanize.Bytes(uint64(-delta))
+	}
+
+	return "+" + humanize.Bytes(uint64(delta))
+}
+
+// captureSizeTree records the sizes in node_modules for the treemap of the
+// HTML report. It has to be called before the temporary directory is removed.
+func captureSizeTree(dir internal.TmpDir) *sizeTree {
+	if *fFormat != formatHTML {
+		return nil
+	}
+
+	tree, err := buildSizeTree(dir.Join("node_modules"))
+	if err != nil {
+		log.Error().Err(err).Str("dir", dir.String()).Msg("Failed to measure installed tree")
+		return nil
+	}
+
+	return tree
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"package_size_calculator/internal"
+	"package_size_calculator/pkg/npm"
+	"slices"
+
+	"github.com/dustin/go-humanize"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// installScript is a package in the tree that runs a preinstall, install or
+// postinstall script.
+type installScript struct {
+	// Path is the key of the package in the lockfile
+	Path    string
+	Package npm.PackageJSON
+	Size    uint64
+	// SizeWithoutScripts is only set if the package was also installed with
+	// --ignore-scripts
+	SizeWithoutScripts *uint64
+}
+
+// findInstallScripts lists the packages of the lockfile that run install
+// scripts, sorted by path.
+func findInstallScripts(lock *npm.PackageLockJSON, sizes map[string]uint64) []installScript {
+	var scripts []installScript
+	for path, pkg := range lock.Packages {
+		if !pkg.HasInstallScripts() {
+			continue
+		}
+
+		scripts = append(scripts, installScript{Path: path, Package: pkg, Size: sizes[path]})
+	}
+
+	slices.SortFunc(scripts, func(a, b installScript) int {
+		return cmp.Compare(a.Path, b.Path)
+	})
+
+	return scripts
+}
+
+// countInstallScripts counts the packages of the lockfile that run install
+// scripts.
+func countInstallScripts(lock *npm.PackageLockJSON) uint64 {
+	var count uint64
+	for _, pkg := range lock.Packages {
+		if pkg.HasInstallScripts() {
+			count++
+		}
+	}
+
+	return count
+}
+
+// countOtherInstallScripts counts the scripts of all packages except the one at
+// the excluded path. The measured package is the unlisted root of modified
+// installs, so it has to be left out when comparing the counts.
+func countOtherInstallScripts(scripts []installScript, excluded string) uint64 {
+	var count uint64
+	for _, s := range scripts {
+		if s.Path != excluded {
+			count++
+		}
+	}
+
+	return count
+}
+
+// measureWithoutScripts installs the package again with --ignore-scripts and
+// records the size of every package with scripts, so that the size the
+// scripts add, e.g. by downloading binaries, can be shown.
+func measureWithoutScripts(package_ npm.DependencyInfo, scripts []installScript) (uint64, error) {
+	opts := defaultInstallOptions()
+	opts.IgnoreScripts = true
+
+	size, tmpDir, err := measurePackageSizeWith(package_, opts)
+	if tmpDir != "" && !*fNoCleanup {
+		defer tmpDir.Remove()
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	lock, err := npm.ParsePackageLockJSON(tmpDir.Join("package-lock.json"))
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to parse package-lock.json")
+	}
+
+	sizes, err := measureLockedPackageSizes(tmpDir.String(), lock)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to measure installed packages")
+	}
+
+	for i := range scripts {
+		if s, ok := sizes[scripts[i].Path]; ok {
+			scripts[i].SizeWithoutScripts = internal.U64Ptr(s)
+		}
+	}
+
+	return size, nil
+}
+
+// compareInstallScripts measures the package without install scripts if any
+// package in its tree has them.
+func compareInstallScripts(b *packageInfo) {
+	if len(b.InstallScripts) == 0 {
+		return
+	}
+
+	if *fInstaller == installerGo {
+		log.Warn().Msg("Install scripts never run with the Go installer, skipping the comparison")
+		return
+	}
+
+	log.Info().Int("packages", len(b.InstallScripts)).Msg("Installing package without install scripts")
+
+	size, err := measureWithoutScripts(b.AsDependency(), b.InstallScripts)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to measure package without install scripts")
+		return
+	}
+
+	b.SizeWithoutScripts = &size
+}
+
+func reportInstallScripts(w io.Writer, p packageSummary) {
+	if len(p.InstallScripts) == 0 {
+		return
+	}
+
+	summary := ""
+	if p.ScriptsSize != nil {
+		summary = " " + grayParens("%s from scripts", fmtSignedBytes(*p.ScriptsSize))
+	}
+
+	fmt.Fprintf(
+		w,
+		"  %s: %s%s\n",
+		bold.Sprint("Install scripts"),
+		pluralize(len(p.InstallScripts), "package", "packages"),
+		summary,
+	)
+
+	for _, s := range p.InstallScripts {
+		size := humanize.Bytes(s.Size)
+		if s.SizeWithoutScripts != nil {
+			size += " " + grayParens("%s from scripts", fmtSignedBytes(int64(s.Size)-int64(*s.SizeWithoutScripts)))
+		}
+
+		fmt.Fprintf(w, "    %s: %s\n", boldYellow.Sprintf("%s@%s", s.Path, s.Package.Version), size)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"package_size_calculator/internal"
+	"package_size_calculator/pkg/npm"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	installerDocker = "docker"
+	installerGo     = "go"
+)
+
+// ErrOverridesUnsupported is returned if overrides would be silently dropped,
+// because only npm applies them.
+var ErrOverridesUnsupported = errors.New("overrides aren't supported by the go installer")
+
+type installOptions struct {
+	// Platform is nil if no platform was requested, npm then installs for the
+	// platform of the container
+	Platform *npm.Platform
+	// IgnoreScripts skips lifecycle scripts, the Go installer never runs them
+	IgnoreScripts bool
+}
+
+func defaultInstallOptions() installOptions {
+	opts := installOptions{}
+	if *fPlatform != "" {
+		opts.Platform = &targetPlatform
+	}
+
+	return opts
+}
+
+// NPMArgs returns the arguments that make npm install with the options.
+func (o installOptions) NPMArgs() []string {
+	var args []string
+	if o.Platform != nil {
+		args = append(args, o.Platform.NPMArgs()...)
+	}
+	if o.IgnoreScripts {
+		args = append(args, "--ignore-scripts")
+	}
+
+	return args
+}
+
+// platform is the platform the Go installer resolves the tree for.
+func (o installOptions) platform() npm.Platform {
+	if o.Platform == nil {
+		return targetPlatform
+	}
+
+	return *o.Platform
+}
+
+func installPackage(package_ npm.DependencyInfo, opts installOptions) (internal.TmpDir, error) {
+	if *fInstaller == installerGo {
+		return installPackageInGo(package_, opts.platform())
+	}
+
+	return installPackageInContainer(package_, opts)
+}
+
+// installPackageInGo installs the package into an empty project like
+// installPackageInContainer, but resolves and extracts the tree without npm.
+func installPackageInGo(package_ npm.DependencyInfo, platform npm.Platform) (internal.TmpDir, error) {
+	tmpDir, err := internal.NewTmpDir(fmt.Sprintf("package_size_%s_*", internal.SanetizeFileName(package_.String())))
+	if err != nil {
+		return tmpDir, err
+	}
+	log.Trace().Str("dir", tmpDir.String()).Msg("Created temp dir")
+
+	// npm names projects without a package.json after their directory
+	root := npm.PackageJSON{Name: filepath.Base(tmpDir.String())}
+	if err := root.AddDependency(package_); err != nil {
+		return tmpDir, err
+	}
+
+	if err := internal.WriteJSONFile(tmpDir.Join("package.json"), root); err != nil {
+		return tmpDir, err
+	}
+
+	return tmpDir, installTreeInGo(root, tmpDir, platform)
+}
+
+// installTreeInGo resolves the dependencies of the project, extracts them into
+// its node_modules directory and writes the lockfile of the installed tree.
+func installTreeInGo(root npm.PackageJSON, tmpDir internal.TmpDir, platform npm.Platform) error {
+	tree := npmClient.ResolveTree(root, platform)
+	for _, d := range tree.Unresolved {
+		log.Warn().Err(d.Err).Str("dependency", d.String()).Msg("Dependency can't be installed without npm, skipping it")
+	}
+
+	lock := tree.Lockfile()
+
+	installer := npmClient.NewInstaller(
+		npm.WithTarballCache(npmCache.String(), npmCacheRO),
+		npm.WithPlatform(platform),
+	)
+	if err := installer.Install(lock, tmpDir.String()); err != nil {
+		return errors.Wrap(err, "failed to install packages")
+	}
+
+	log.Debug().Int("packages", len(lock.Packages)).Msg("Installed packages without npm")
+
+	return internal.WriteJSONFile(tmpDir.Join("package-lock.json"), lock)
+}
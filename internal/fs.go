@@ -25,6 +25,30 @@ func DirSize(path string) (uint64, error) {
 	return size, err
 }
 
+// PackageDirSize measures the size of a package directory without the
+// packages nested in its node_modules directories.
+func PackageDirSize(path string) (uint64, error) {
+	var size uint64 = 0
+
+	err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() && info.Name() == "node_modules" && p != path {
+			return filepath.SkipDir
+		}
+
+		if !info.IsDir() {
+			size += uint64(info.Size())
+		}
+
+		return err
+	})
+
+	return size, err
+}
+
 func SanetizeFileName(path string) string {
 	path = strings.ReplaceAll(path, "/", "_")
 	path = strings.ReplaceAll(path, "\\", "_")
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"package_size_calculator/internal/build"
+
+	"github.com/pkg/errors"
+)
+
+// savedReport is the document of JSON reports, which can be compared with the
+// diff command later.
+type savedReport struct {
+	// Version is the version of the calculator that wrote the report
+	Version string
+	Report  *Report
+}
+
+// jsonRenderer writes the whole report as JSON for other tools.
+type jsonRenderer struct{}
+
+func (jsonRenderer) Render(w io.Writer, r *Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(savedReport{Version: build.Version, Report: r})
+}
+
+// loadReport reads a report that was written with -format json.
+func loadReport(path string) (*savedReport, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open report")
+	}
+	defer f.Close()
+
+	var s savedReport
+	if err := json.NewDecoder(f).Decode(&s); err != nil {
+		return nil, errors.Wrap(err, "failed to decode report")
+	}
+	if s.Report == nil {
+		return nil, errors.Errorf("%s isn't a JSON report", path)
+	}
+
+	return &s, nil
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"package_size_calculator/pkg/npm"
+	"slices"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/rs/zerolog/log"
+)
+
+// maxLicensePackagesShown limits the packages listed for flagged licenses
+const maxLicensePackagesShown = 5
+
+// unknownLicense is shown for packages without a license
+const unknownLicense = "UNKNOWN"
+
+// copyleftLicensePrefixes are the prefixes of the SPDX identifiers of copyleft
+// licenses, which require derived works to use the same license
+var copyleftLicensePrefixes = []string{"AGPL", "GPL", "LGPL", "MPL", "EPL", "EUPL", "CDDL", "OSL", "CC-BY-SA", "SSPL"}
+
+type licenseStatus uint8
+
+// The statuses are ordered from the least to the most concerning
+const (
+	licenseOK licenseStatus = iota
+	licenseCopyleft
+	licenseUnknown
+	licenseDenied
+)
+
+func (s licenseStatus) String() string {
+	switch s {
+	case licenseOK:
+		return "ok"
+	case licenseCopyleft:
+		return "copyleft"
+	case licenseUnknown:
+		return "unknown"
+	case licenseDenied:
+		return "denied"
+	}
+
+	return "unknown"
+}
+
+// licensePolicy flags licenses. Allowed licenses are never flagged, denied
+// licenses always are. If licenses are allowed explicitly, every other license
+// is denied.
+type licensePolicy struct {
+	Allow map[string]struct{}
+	Deny  map[string]struct{}
+}
+
+func newLicensePolicy(allow, deny string) licensePolicy {
+	return licensePolicy{
+		Allow: parseLicenseList(allow),
+		Deny:  parseLicenseList(deny),
+	}
+}
+
+func parseLicenseList(s string) map[string]struct{} {
+	list := map[string]struct{}{}
+	for _, id := range strings.Split(s, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			list[strings.ToUpper(id)] = struct{}{}
+		}
+	}
+
+	return list
+}
+
+// Check flags the license expression. Of alternatives ("OR") the least
+// concerning one counts, of combinations ("AND") the most concerning one.
+func (p licensePolicy) Check(l npm.License) licenseStatus {
+	e, err := l.Parse()
+	if err != nil {
+		log.Trace().Err(err).Str("license", string(l)).Msg("Failed to parse license")
+		return p.checkID(string(l))
+	}
+
+	return p.checkExpression(e)
+}
+
+func (p licensePolicy) checkExpression(e npm.LicenseExpression) licenseStatus {
+	if e.Operator == "" {
+		return p.checkID(e.IDs()[0])
+	}
+
+	statuses := make([]licenseStatus, 0, len(e.Operands))
+	for _, o := range e.Operands {
+		statuses = append(statuses, p.checkExpression(o))
+	}
+
+	if e.Operator == npm.LicenseOperatorOr {
+		return slices.Min(statuses)
+	}
+
+	return slices.Max(statuses)
+}
+
+func (p licensePolicy) checkID(id string) licenseStatus {
+	id = strings.ToUpper(strings.TrimSpace(id))
+
+	if _, ok := p.Deny[id]; ok {
+		return licenseDenied
+	}
+	if _, ok := p.Allow[id]; ok {
+		return licenseOK
+	}
+	if len(p.Allow) > 0 {
+		return licenseDenied
+	}
+
+	// Identifiers with spaces aren't SPDX identifiers, e.g. "SEE LICENSE IN
+	// LICENSE.md"
+	if id == "" || id == unknownLicense || id == "UNLICENSED" || strings.Contains(id, " ") {
+		return licenseUnknown
+	}
+
+	for _, prefix := range copyleftLicensePrefixes {
+		if strings.HasPrefix(id, prefix) {
+			return licenseCopyleft
+		}
+	}
+
+	return licenseOK
+}
+
+// licenseInventory maps the licenses in a tree to the packages using them.
+type licenseInventory map[npm.License][]string
+
+// collectLicenses collects the licenses of the packages in the lockfile. The
+// package at the excluded path isn't included, so that the tree of an
+// installed package can be compared to the tree of the package installed as
+// the root project.
+func collectLicenses(lock *npm.PackageLockJSON, excluded string) licenseInventory {
+	inventory := licenseInventory{}
+	for path, pkg := range lock.Packages {
+		if pkg.Link || path == excluded {
+			continue
+		}
+
+		license := npm.License(strings.TrimSpace(string(pkg.License)))
+		if license == "" {
+			license = unknownLicense
+		}
+
+		inventory[license] = append(inventory[license], npm.LockedPackageName(path, pkg)+"@"+pkg.Version)
+	}
+
+	for _, packages := range inventory {
+		slices.Sort(packages)
+	}
+
+	return inventory
+}
+
+// Sorted returns the licenses sorted by the number of packages using them.
+func (i licenseInventory) Sorted() []npm.License {
+	licenses := make([]npm.License, 0, len(i))
+	for l := range i {
+		licenses = append(licenses, l)
+	}
+
+	slices.SortFunc(licenses, func(a, b npm.License) int {
+		return cmp.Or(cmp.Compare(len(i[b]), len(i[a])), cmp.Compare(a, b))
+	})
+
+	return licenses
+}
+
+func licenseStatusLabel(s licenseStatus) string {
+	switch s {
+	case licenseCopyleft:
+		return " " + color.YellowString("(copyleft)")
+	case licenseUnknown:
+		return " " + color.YellowString("(unknown)")
+	case licenseDenied:
+		return " " + color.RedString("(denied)")
+	}
+
+	return ""
+}
+
+func formatLicensePackages(packages []string) string {
+	if len(packages) <= maxLicensePackagesShown {
+		return strings.Join(packages, ", ")
+	}
+
+	return fmt.Sprintf("%s and %d more", strings.Join(packages[:maxLicensePackagesShown], ", "), len(packages)-maxLicensePackagesShown)
+}
+
+// summarizeLicenses checks the licenses of the inventory against the license
+// policy.
+func summarizeLicenses(inventory licenseInventory) []licenseSummary {
+	summaries := make([]licenseSummary, 0, len(inventory))
+	for _, l := range inventory.Sorted() {
+		summaries = append(summaries, licenseSummary{License: l, Status: activeLicensePolicy.Check(l), Packages: inventory[l]})
+	}
+
+	return summaries
+}
+
+// newLicenseChanges lists the licenses that a modification introduces or
+// removes from the tree.
+func newLicenseChanges(oldInventory, newInventory licenseInventory) []licenseChange {
+	if oldInventory == nil || newInventory == nil {
+		return nil
+	}
+
+	var changes []licenseChange
+	for _, l := range newInventory.Sorted() {
+		if _, ok := oldInventory[l]; !ok {
+			changes = append(changes, licenseChange{License: l, Status: activeLicensePolicy.Check(l), Added: true, Packages: newInventory[l]})
+		}
+	}
+	for _, l := range oldInventory.Sorted() {
+		if _, ok := newInventory[l]; !ok {
+			changes = append(changes, licenseChange{License: l, Status: activeLicensePolicy.Check(l), Packages: oldInventory[l]})
+		}
+	}
+
+	return changes
+}
+
+func reportLicenses(w io.Writer, licenses []licenseSummary) {
+	if len(licenses) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "  %s: %s\n", bold.Sprint("Licenses"), fmtInt(int64(len(licenses))))
+
+	for _, l := range licenses {
+		line := fmt.Sprintf("    %s%s: %s", bold.Sprint(l.License), licenseStatusLabel(l.Status), pluralize(len(l.Packages), "package", "packages"))
+		if l.Status != licenseOK {
+			line += " " + grayParens("%s", formatLicensePackages(l.Packages))
+		}
+
+		fmt.Fprintln(w, line)
+	}
+}
+
+func reportLicenseChanges(w io.Writer, changes []licenseChange) {
+	if len(changes) == 0 {
+		return
+	}
+
+	bold.Fprintln(w, "  License changes:")
+	for _, c := range changes {
+		if c.Added {
+			fmt.Fprintf(
+				w,
+				"    %s %s%s: %s\n",
+				color.GreenString("+"),
+				bold.Sprint(c.License),
+				licenseStatusLabel(c.Status),
+				formatLicensePackages(c.Packages),
+			)
+
+			continue
+		}
+
+		fmt.Fprintf(
+			w,
+			"    %s %s: %s\n",
+			color.RedString("-"),
+			bold.Sprint(c.License),
+			formatLicensePackages(c.Packages),
+		)
+	}
+}
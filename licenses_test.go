@@ -0,0 +1,72 @@
+package main
+
+import (
+	"package_size_calculator/pkg/npm"
+	"testing"
+)
+
+func TestLicensePolicyCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		allow   string
+		deny    string
+		license npm.License
+		want    licenseStatus
+	}{
+		{name: "permissive", license: "MIT", want: licenseOK},
+		{name: "copyleft", license: "GPL-3.0-only", want: licenseCopyleft},
+		{name: "lowercase copyleft", license: "lgpl-2.1", want: licenseCopyleft},
+		{name: "missing", license: "", want: licenseUnknown},
+		{name: "unknown", license: unknownLicense, want: licenseUnknown},
+		{name: "unlicensed", license: "UNLICENSED", want: licenseUnknown},
+		{name: "see license in", license: "SEE LICENSE IN LICENSE.md", want: licenseUnknown},
+		{name: "invalid expression", license: "MIT OR", want: licenseUnknown},
+		{name: "or picks the least concerning", license: "MIT OR GPL-3.0-only", want: licenseOK},
+		{name: "and picks the most concerning", license: "MIT AND GPL-3.0-only", want: licenseCopyleft},
+		{name: "parentheses", license: "(MIT OR GPL-3.0-only) AND MPL-2.0", want: licenseCopyleft},
+		{name: "precedence", license: "MIT OR GPL-3.0-only AND MPL-2.0", want: licenseOK},
+		{name: "exception", license: "GPL-2.0-only WITH Classpath-exception-2.0", want: licenseCopyleft},
+		{name: "denied", deny: "gpl-3.0-only", license: "GPL-3.0-only", want: licenseDenied},
+		{name: "denied alternative", deny: "GPL-3.0-only", license: "MIT OR GPL-3.0-only", want: licenseOK},
+		{name: "denied combination", deny: "GPL-3.0-only", license: "MIT AND GPL-3.0-only", want: licenseDenied},
+		{name: "denied with exception", deny: "GPL-2.0-only", license: "GPL-2.0-only WITH Classpath-exception-2.0", want: licenseDenied},
+		{name: "allowed", allow: "MIT, ISC", license: "ISC", want: licenseOK},
+		{name: "not allowed", allow: "MIT, ISC", license: "Apache-2.0", want: licenseDenied},
+		{name: "allowed copyleft", allow: "GPL-3.0-only", license: "GPL-3.0-only", want: licenseOK},
+		{name: "unknown isn't allowed", allow: "MIT", license: "", want: licenseDenied},
+		{name: "deny wins over allow", allow: "MIT", deny: "MIT", license: "MIT", want: licenseDenied},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := newLicensePolicy(test.allow, test.deny)
+			if got := p.Check(test.license); got != test.want {
+				t.Errorf("status of %q = %s, want %s", test.license, got, test.want)
+			}
+		})
+	}
+}
+
+func TestCollectLicenses(t *testing.T) {
+	lock := &npm.PackageLockJSON{
+		Packages: npm.LockedPackages{
+			"pkg": {Version: "1.0.0", License: "MIT"},
+			"a":   {Version: "1.0.0", License: " MIT "},
+			"b":   {Version: "2.0.0"},
+			"c":   {Version: "3.0.0", License: "ISC"},
+			"d":   {Version: "1.0.0", License: "ISC", Link: true},
+		},
+	}
+
+	inventory := collectLicenses(lock, "pkg")
+
+	if got := inventory["MIT"]; len(got) != 1 || got[0] != "a@1.0.0" {
+		t.Errorf("MIT packages = %v, want only a@1.0.0 without the excluded package", got)
+	}
+	if got := inventory[unknownLicense]; len(got) != 1 || got[0] != "b@2.0.0" {
+		t.Errorf("%s packages = %v, want b@2.0.0", unknownLicense, got)
+	}
+	if got := inventory["ISC"]; len(got) != 1 || got[0] != "c@3.0.0" {
+		t.Errorf("ISC packages = %v, want c@3.0.0 without the link", got)
+	}
+}
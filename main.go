@@ -24,10 +24,35 @@ var (
 	npmCache   internal.TmpDir
 	npmCacheRO = false
 
+	// targetPlatform is the platform packages are installed for
+	targetPlatform = npm.DefaultPlatform
+	// activeLicensePolicy flags licenses in the reports
+	activeLicensePolicy licensePolicy
+	// activeEgressPrice is the price of traffic, nil if costs aren't estimated
+	activeEgressPrice *egressPrice
+	// activeEnergyModel estimates energy and emissions, nil if they aren't
+	// estimated
+	activeEnergyModel *energyModel
+
 	fShortMode  = flag.Bool("short", false, "Print a shorter version of the package report, ideal for posts to Twitter")
 	fNoCleanup  = flag.Bool("no-cleanup", false, "Do not cleanup the temporary directories after the execution")
 	fNPMCache   = flag.String("npm-cache", "", "Use the specified directory as the NPM cache")
 	fNPMCacheRW = flag.Bool("npm-cache-rw", true, "Mount the NPM cache directory as read-write")
+
+	fPlatform        = flag.String("platform", "", "Install packages for the platform in the format os/cpu[/libc], e.g. darwin/arm64 or linux/x64/musl")
+	fInstaller       = flag.String("installer", installerDocker, "Install packages with npm in a Docker container (\"docker\") or by extracting their tarballs without running scripts (\"go\")")
+	fLicenseAllow    = flag.String("license-allow", "", "Comma-separated SPDX identifiers of allowed licenses, all other licenses are flagged")
+	fLicenseDeny     = flag.String("license-deny", "", "Comma-separated SPDX identifiers of licenses that are flagged")
+	fCompareScripts  = flag.Bool("compare-scripts", false, "Also install the package with --ignore-scripts and show the size install scripts add")
+	fCompareEstimate = flag.Bool("compare-estimate", false, "Also estimate the package size from the registry and compare it to the measured size")
+	fDeprecated      = flag.Bool("deprecated", false, "Check the installed packages for deprecated versions, which fetches the package info of every package in the tree")
+	fFormat          = flag.String("format", formatText, "Format of the report, \"text\" for the terminal, \"html\" for a standalone page with a treemap, \"svg\"/\"png\" for an image to share, \"csv\"/\"tsv\" for spreadsheets or \"json\" for other tools and the diff command")
+	fEgressPrice     = flag.String("egress-price", "", "Estimate the cost of traffic with the price in USD per GB or a preset (aws, azure, gcp, cloudflare)")
+	fEnergy          = flag.String("energy", "", "Estimate the energy and emissions of traffic and installs, \"default\" or key=value pairs overriding the default model (network, storage, retention, intensity)")
+	fVersionMix      = flag.Int("version-mix", 0, "Weigh the traffic of all versions with the sizes of the N most downloaded versions, which are estimated from the registry unless they were measured")
+	fHistoryReleases = flag.String("history-releases", "", "Versions the history command measures: \"all\", or the last release of each \"minor\" or \"major\" version. Defaults to all versions in a range and minor versions otherwise")
+	fHistoryCache    = flag.String("history-cache", "", "File the history command caches measurements in, defaults to a file in the user cache directory")
+	fOutput          = flag.String("output", "", "File the report is written to, text reports are printed if it's empty and other formats are written to \"report.<format>\". CSV and TSV reports are appended")
 )
 
 func main() {
@@ -42,39 +67,45 @@ func main() {
 
 	npmClient = npm.New()
 
+	if *fInstaller != installerDocker && *fInstaller != installerGo {
+		log.Fatal().Str("installer", *fInstaller).Msg("Unknown installer")
+	}
+
+	if _, ok := defaultOutputs[*fFormat]; !ok && *fFormat != formatText {
+		log.Fatal().Str("format", *fFormat).Msg("Unknown report format")
+	}
+
+	activeLicensePolicy = newLicensePolicy(*fLicenseAllow, *fLicenseDeny)
+
 	var err error
-	dockerC, err = docker_client.NewClientWithOpts(docker_client.FromEnv, docker_client.WithAPIVersionNegotiation())
+	activeEgressPrice, err = parseEgressPrice(*fEgressPrice)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to create Docker client")
+		log.Fatal().Err(err).Msg("Failed to parse egress price")
 	}
-	log.Info().Msgf("Pulling %s image for measuring package sizes", BaseImage)
-	if err := downloadBaseImage(dockerC); err != nil {
-		log.Fatal().Err(err).Msg("Failed to download Node 22 image")
+
+	activeEnergyModel, err = parseEnergyModel(*fEnergy)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse energy model")
 	}
 
-	if *fNPMCache == "" {
-		npmCache, err = internal.NewTmpDir("npm_cache_*")
+	if *fPlatform != "" {
+		targetPlatform, err = npm.ParsePlatform(*fPlatform)
 		if err != nil {
-			log.Fatal().Err(err).Msg("Failed to create temporary directory for NPM cache")
+			log.Fatal().Err(err).Msg("Failed to parse platform")
 		}
-		defer func() {
-			if !*fNoCleanup {
-				return
-			}
 
-			npmCache.Remove()
-
-			log.Debug().Str("dir", npmCache.String()).Msg("Cleaned up temporary directory for NPM cache")
-		}()
-
-		log.Debug().Str("dir", npmCache.String()).Msg("Created temporary directory for NPM cache")
-	} else {
-		npmCache = internal.TmpDir(*fNPMCache)
-		npmCacheRO = !*fNPMCacheRW
+		log.Info().Str("platform", targetPlatform.String()).Msg("Installing packages for platform")
+	}
 
-		log.Debug().Str("dir", npmCache.String()).Bool("readonly", npmCacheRO).Msg("Using specified directory as NPM cache")
+	args := flag.Args()
+	if len(args) > 0 {
+		runCommand(args)
+		return
 	}
 
+	setupDocker()
+	defer setupNPMCache()()
+
 	variant, _, err := internal.RunSelect(&promptui.Select{
 		Label: "Select variant",
 		Items: []string{"Calculate size differences for replacing/removing dependencies", "Calculate size difference between package versions"},
@@ -90,3 +121,108 @@ func main() {
 		calculateVersionSizeChange()
 	}
 }
+
+func runCommand(args []string) {
+	switch args[0] {
+	case "why", "platforms", "estimate", "diff", "history":
+		requireTextFormat(args[0])
+	}
+
+	switch args[0] {
+	case "why":
+		if len(args) < 2 || len(args) > 3 {
+			log.Fatal().Msg("Usage: package-size-calculator why <package> [project directory]")
+		}
+
+		if len(args) == 3 {
+			explainWhyInProject(args[1], args[2])
+			return
+		}
+
+		setupDocker()
+		defer setupNPMCache()()
+
+		explainWhyInPackage(args[1])
+	case "platforms":
+		setupDocker()
+		defer setupNPMCache()()
+
+		comparePlatforms(args[1:])
+	case "estimate":
+		if len(args) > 2 {
+			log.Fatal().Msg("Usage: package-size-calculator estimate [package]")
+		}
+
+		spec := ""
+		if len(args) == 2 {
+			spec = args[1]
+		}
+
+		estimatePackage(spec)
+	case "diff":
+		if len(args) != 3 {
+			log.Fatal().Msg("Usage: package-size-calculator diff <old.json> <new.json>")
+		}
+
+		diffReports(args[1], args[2])
+	case "history":
+		if len(args) != 2 {
+			log.Fatal().Msg("Usage: package-size-calculator history <package>[@range]")
+		}
+
+		setupDocker()
+		defer setupNPMCache()()
+
+		packageHistory(args[1])
+	default:
+		log.Fatal().Str("command", args[0]).Msg("Unknown command")
+	}
+}
+
+func setupDocker() {
+	if *fInstaller == installerGo {
+		log.Info().Msg("Installing packages without Docker, install scripts won't run")
+		return
+	}
+
+	var err error
+	dockerC, err = docker_client.NewClientWithOpts(docker_client.FromEnv, docker_client.WithAPIVersionNegotiation())
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to create Docker client")
+	}
+	log.Info().Msgf("Pulling %s image for measuring package sizes", BaseImage)
+	if err := downloadBaseImage(dockerC); err != nil {
+		log.Fatal().Err(err).Msg("Failed to download Node 22 image")
+	}
+}
+
+// setupNPMCache creates or selects the NPM cache directory and returns a
+// function that cleans it up.
+func setupNPMCache() func() {
+	if *fNPMCache != "" {
+		npmCache = internal.TmpDir(*fNPMCache)
+		npmCacheRO = !*fNPMCacheRW
+
+		log.Debug().Str("dir", npmCache.String()).Bool("readonly", npmCacheRO).Msg("Using specified directory as NPM cache")
+
+		return func() {}
+	}
+
+	var err error
+	npmCache, err = internal.NewTmpDir("npm_cache_*")
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to create temporary directory for NPM cache")
+	}
+
+	log.Debug().Str("dir", npmCache.String()).Msg("Created temporary directory for NPM cache")
+
+	return func() {
+		if !*fNoCleanup {
+			return
+		}
+
+		npmCache.Remove()
+
+		log.Debug().Str("dir", npmCache.String()).Msg("Cleaned up temporary directory for NPM cache")
+	}
+}
@@ -1,17 +1,25 @@
 package main
 
 import (
+	"io/fs"
 	"package_size_calculator/internal"
 	"package_size_calculator/pkg/npm"
+	"path/filepath"
 
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 )
 
 func measurePackageSize(package_ npm.DependencyInfo) (uint64, internal.TmpDir, error) {
-	l := log.With().Str("package", package_.String()).Logger()
+	return measurePackageSizeWith(package_, defaultInstallOptions())
+}
+
+// measurePackageSizeWith measures the size of the package installed with the
+// options, e.g. for another platform.
+func measurePackageSizeWith(package_ npm.DependencyInfo, opts installOptions) (uint64, internal.TmpDir, error) {
+	l := log.With().Str("package", package_.String()).Str("platform", opts.Platform.String()).Bool("ignoreScripts", opts.IgnoreScripts).Logger()
 
-	tmpDir, err := installPackageInContainer(package_)
+	tmpDir, err := installPackage(package_, opts)
 	if err != nil {
 		return 0, tmpDir, errors.Wrapf(err, "failed to install package \"%s\"", package_.String())
 	}
@@ -27,3 +35,71 @@ func measurePackageSize(package_ npm.DependencyInfo) (uint64, internal.TmpDir, e
 
 	return bytes, tmpDir, nil
 }
+
+// measureLockedPackageSizes measures the size of every package in the
+// lockfile of the project in dir, keyed by its path in the lockfile.
+func measureLockedPackageSizes(dir string, lock *npm.PackageLockJSON) (map[string]uint64, error) {
+	sizes := make(map[string]uint64, len(lock.Packages))
+
+	for path, pkg := range lock.Packages {
+		// Links point to packages that are measured on their own
+		if pkg.Link {
+			continue
+		}
+
+		location := pkg.Location
+		if location == "" {
+			location = filepath.Join("node_modules", path)
+		}
+
+		size, err := internal.PackageDirSize(filepath.Join(dir, filepath.FromSlash(location)))
+		if errors.Is(err, fs.ErrNotExist) {
+			// Optional dependencies for other platforms are locked, but not installed
+			continue
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "failed to measure size of \"%s\"", path)
+		}
+
+		sizes[path] = size
+	}
+
+	return sizes, nil
+}
+
+// measureModifiedPackage installs the package with the given modifications as
+// the root project and measures the installed tree.
+func measureModifiedPackage(p npm.PackageJSON, toAdd, toRemove []npm.DependencyInfo, overrides []npm.Override) (ModifiedStats, error) {
+	var s ModifiedStats
+
+	tmpDir, err := modifyPackage(p, toAdd, toRemove, overrides)
+	if err != nil {
+		return s, errors.Wrap(err, "failed to modify package")
+	}
+	if !*fNoCleanup {
+		defer tmpDir.Remove()
+	}
+
+	s.Size, err = internal.DirSize(tmpDir.Join("node_modules"))
+	if err != nil {
+		return s, errors.Wrap(err, "failed to measure new package size")
+	}
+
+	lock, err := npm.ParsePackageLockJSON(tmpDir.Join("package-lock.json"))
+	if err != nil {
+		return s, errors.Wrap(err, "failed to parse new package-lock.json")
+	}
+
+	s.Subdependencies = uint64(len(lock.Packages))
+	s.InstallScripts = countInstallScripts(lock)
+	s.Deprecated = uint64(len(findDeprecated(lock)))
+	s.Licenses = collectLicenses(lock, "")
+
+	s.Kinds, err = measureKindBreakdown(tmpDir, lock, "")
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to measure new dependency kinds")
+	}
+
+	s.Tree = captureSizeTree(tmpDir)
+
+	return s, nil
+}
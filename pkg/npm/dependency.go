@@ -5,8 +5,42 @@ import "fmt"
 type DependencyInfo struct {
 	Name    string
 	Version string
+	// Spec is only set for dependencies that aren't plain registry
+	// dependencies, like aliases, git repositories or local paths
+	Spec *Spec
 }
 
 func (d DependencyInfo) String() string {
-	return fmt.Sprintf("%s@%s", d.Name, d.Version)
+	return fmt.Sprintf("%s@%s", d.Name, d.RawSpec())
+}
+
+// RawSpec returns the spec the dependency can be installed with.
+func (d DependencyInfo) RawSpec() string {
+	if d.Spec == nil {
+		return d.Version
+	}
+
+	if d.Spec.Type == SpecTypeAlias {
+		return fmt.Sprintf("%s%s@%s", aliasPrefix, d.Spec.SubSpec.Name, d.Version)
+	}
+
+	return d.Spec.RawSpec
+}
+
+func (d DependencyInfo) SpecType() SpecType {
+	if d.Spec == nil {
+		return SpecTypeVersion
+	}
+
+	return d.Spec.Type
+}
+
+// RegistryName returns the name the package is published under in the
+// registry, which differs from Name for aliases.
+func (d DependencyInfo) RegistryName() string {
+	if d.Spec == nil {
+		return d.Name
+	}
+
+	return d.Spec.RegistryName()
 }
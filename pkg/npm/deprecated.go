@@ -0,0 +1,137 @@
+package npm
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Deprecation is the message a version was deprecated with. Some packuments
+// contain other values than messages, which are ignored.
+type Deprecation string
+
+func (d *Deprecation) UnmarshalJSON(data []byte) error {
+	var message string
+	if err := json.Unmarshal(data, &message); err != nil {
+		*d = ""
+		return nil
+	}
+
+	*d = Deprecation(message)
+
+	return nil
+}
+
+type DeprecatedPackage struct {
+	// Path is the key of the package in the lockfile
+	Path    string
+	Name    string
+	Version string
+	Message string
+}
+
+func (p DeprecatedPackage) String() string {
+	return p.Name + "@" + p.Version
+}
+
+// FindDeprecated looks up every package of the lockfile in its packument and
+// returns the deprecated ones, sorted by path. Packages that aren't from the
+// registry are skipped. Packuments that can't be fetched are reported as an
+// error together with the packages that could be checked.
+func (c *Client) FindDeprecated(lock *PackageLockJSON) ([]DeprecatedPackage, error) {
+	byName := map[string][]string{}
+	for path, pkg := range lock.Packages {
+		if pkg.Link || !isRegistryTarball(pkg.Resolved) {
+			continue
+		}
+
+		name := LockedPackageName(path, pkg)
+		byName[name] = append(byName[name], path)
+	}
+
+	var (
+		wg         sync.WaitGroup
+		mu         sync.Mutex
+		errs       []error
+		deprecated []DeprecatedPackage
+		sem        = make(chan struct{}, maxConcurrentResolves)
+	)
+
+	for name, paths := range byName {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			info, err := c.GetPackageInfo(name)
+			<-sem
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to fetch package info of \"%s\": %w", name, err))
+				return
+			}
+
+			for _, path := range paths {
+				version := lock.Packages[path].Version
+
+				v, ok := info.Versions[version]
+				if !ok || v.JSON.Deprecated == "" {
+					continue
+				}
+
+				deprecated = append(deprecated, DeprecatedPackage{
+					Path:    path,
+					Name:    name,
+					Version: version,
+					Message: string(v.JSON.Deprecated),
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	slices.SortFunc(deprecated, func(a, b DeprecatedPackage) int {
+		return cmp.Compare(a.Path, b.Path)
+	})
+
+	return deprecated, errors.Join(errs...)
+}
+
+// LockedPackageName returns the name of a lockfile entry in the registry.
+// Aliased packages are installed under another name, which is why the name is
+// taken from the tarball URL, e.g. ".../@scope/name/-/name-1.0.0.tgz".
+func LockedPackageName(path string, pkg PackageJSON) string {
+	installed := path
+	if i := strings.LastIndex(path, nodeModulesPrefix); i != -1 {
+		installed = path[i+len(nodeModulesPrefix):]
+	}
+
+	u, err := url.Parse(pkg.Resolved)
+	if err != nil {
+		return installed
+	}
+
+	before, _, ok := strings.Cut(u.Path, "/-/")
+	if !ok {
+		return installed
+	}
+
+	segments := strings.Split(strings.TrimPrefix(before, "/"), "/")
+	if n := len(segments); n >= 2 && strings.HasPrefix(segments[n-2], "@") {
+		return segments[n-2] + "/" + segments[n-1]
+	}
+
+	return segments[len(segments)-1]
+}
+
+func isRegistryTarball(resolved string) bool {
+	return strings.Contains(resolved, "/-/") && hasAnyPrefix(resolved, remotePrefixes)
+}
@@ -2,9 +2,22 @@ package npm
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
 	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/puzpuzpuz/xsync/v3"
+	"github.com/rs/zerolog/log"
 )
 
+// The download counts API refuses bulk queries with more than 128 packages
+// and doesn't support bulk queries for scoped packages at all.
+// https://github.com/npm/registry/blob/main/docs/download-counts.md#bulk-queries
+const maxBulkDownloadsQuerySize = 128
+
 func (n *Client) GetPackageDownloadsLastWeek(packageName string) (Downloads, error) {
 	resp, err := n.c.Get(n.apiBase + "/versions/" + url.PathEscape(packageName) + "/last-week")
 	if err != nil {
@@ -23,6 +36,220 @@ func (n *Client) GetPackageDownloadsLastWeek(packageName string) (Downloads, err
 	return Downloads(info.Downloads), nil
 }
 
+// maxConcurrentDownloadQueries limits the download count queries running at
+// once, the API rate limits clients that send too many
+const maxConcurrentDownloadQueries = 16
+
+// GetPackagesDownloadsLastWeek fetches the per-version download counts of many
+// packages in parallel. The API has no bulk queries for per-version counts, so
+// this should only be used for packages that need them, see
+// GetPackagesDownloadTotalsLastWeek otherwise. Packages whose download counts
+// couldn't be fetched are missing from the returned map and their errors are
+// joined into the returned error.
+func (n *Client) GetPackagesDownloadsLastWeek(packageNames []string) (map[string]Downloads, error) {
+	result := xsync.NewMapOf[string, Downloads]()
+	errs := xsync.NewMapOf[string, error]()
+
+	wg := sync.WaitGroup{}
+	sem := make(chan struct{}, maxConcurrentDownloadQueries)
+	for _, name := range uniqueNames(packageNames) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			downloads, err := n.GetPackageDownloadsLastWeek(name)
+			<-sem
+
+			if err != nil {
+				errs.Store(name, fmt.Errorf("failed to fetch downloads of \"%s\": %w", name, err))
+				return
+			}
+
+			result.Store(name, downloads)
+		}()
+	}
+	wg.Wait()
+
+	return collectDownloads(result, errs)
+}
+
+// GetPackagesDownloadTotalsLastWeek fetches last week's downloads of all
+// versions of many packages. Unscoped packages are batched into bulk queries,
+// scoped packages are fetched one by one in parallel. Packages whose download
+// counts couldn't be fetched are missing from the returned map and their
+// errors are joined into the returned error.
+func (n *Client) GetPackagesDownloadTotalsLastWeek(packageNames []string) (map[string]uint64, error) {
+	var scoped, unscoped []string
+	for _, name := range uniqueNames(packageNames) {
+		if strings.HasPrefix(name, "@") {
+			scoped = append(scoped, name)
+		} else {
+			unscoped = append(unscoped, name)
+		}
+	}
+
+	result := xsync.NewMapOf[string, uint64]()
+	errs := xsync.NewMapOf[string, error]()
+
+	wg := sync.WaitGroup{}
+	sem := make(chan struct{}, maxConcurrentDownloadQueries)
+
+	fetchSingle := func(name string) {
+		defer wg.Done()
+
+		sem <- struct{}{}
+		total, err := n.getPackageDownloadTotalLastWeek(name)
+		<-sem
+
+		if err != nil {
+			errs.Store(name, fmt.Errorf("failed to fetch downloads of \"%s\": %w", name, err))
+			return
+		}
+
+		result.Store(name, total)
+	}
+
+	for start := 0; start < len(unscoped); start += maxBulkDownloadsQuerySize {
+		chunk := unscoped[start:min(start+maxBulkDownloadsQuerySize, len(unscoped))]
+
+		// A bulk query with a single package returns the regular response
+		if len(chunk) == 1 {
+			wg.Add(1)
+			go fetchSingle(chunk[0])
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			totals, err := n.getBulkPackageDownloadTotalsLastWeek(chunk)
+			<-sem
+
+			if err != nil {
+				log.Warn().Err(err).Int("packages", len(chunk)).Msg("Bulk download count query failed, fetching packages one by one")
+
+				wg.Add(len(chunk))
+				for _, name := range chunk {
+					go fetchSingle(name)
+				}
+
+				return
+			}
+
+			for _, name := range chunk {
+				total, ok := totals[name]
+				if !ok {
+					errs.Store(name, fmt.Errorf("bulk query is missing downloads of \"%s\"", name))
+					continue
+				}
+
+				result.Store(name, total)
+			}
+		}()
+	}
+
+	wg.Add(len(scoped))
+	for _, name := range scoped {
+		go fetchSingle(name)
+	}
+
+	wg.Wait()
+
+	return collectDownloads(result, errs)
+}
+
+// downloadPoint is the response of the point endpoint for a single package.
+type downloadPoint struct {
+	Downloads uint64 `json:"downloads"`
+}
+
+func (n *Client) getPackageDownloadTotalLastWeek(packageName string) (uint64, error) {
+	resp, err := n.c.Get(n.apiBase + "/downloads/point/last-week/" + url.PathEscape(packageName))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	var point downloadPoint
+	if err := json.NewDecoder(resp.Body).Decode(&point); err != nil {
+		return 0, err
+	}
+
+	return point.Downloads, nil
+}
+
+func (n *Client) getBulkPackageDownloadTotalsLastWeek(packageNames []string) (map[string]uint64, error) {
+	escaped := make([]string, len(packageNames))
+	for i, name := range packageNames {
+		escaped[i] = url.PathEscape(name)
+	}
+
+	resp, err := n.c.Get(n.apiBase + "/downloads/point/last-week/" + strings.Join(escaped, ","))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	var points map[string]*downloadPoint
+	if err := json.NewDecoder(resp.Body).Decode(&points); err != nil {
+		return nil, err
+	}
+
+	totals := make(map[string]uint64, len(points))
+	for name, p := range points {
+		// Unknown packages are returned as null
+		if p == nil {
+			continue
+		}
+
+		totals[name] = p.Downloads
+	}
+
+	return totals, nil
+}
+
+func uniqueNames(names []string) []string {
+	unique := make([]string, 0, len(names))
+	seen := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+
+		unique = append(unique, name)
+	}
+
+	return unique
+}
+
+func collectDownloads[T any](result *xsync.MapOf[string, T], errs *xsync.MapOf[string, error]) (map[string]T, error) {
+	downloads := make(map[string]T, result.Size())
+	result.Range(func(name string, d T) bool {
+		downloads[name] = d
+		return true
+	})
+
+	var joined []error
+	errs.Range(func(_ string, err error) bool {
+		joined = append(joined, err)
+		return true
+	})
+
+	return downloads, errors.Join(joined...)
+}
+
 type Downloads map[string]uint64
 
 func (d Downloads) ForVersion(version string) (uint64, bool) {
@@ -0,0 +1,148 @@
+package npm
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// downloadsTestServer stubs the download counts API and records the paths it
+// was queried with
+type downloadsTestServer struct {
+	*httptest.Server
+
+	mu    sync.Mutex
+	paths []string
+}
+
+func newDownloadsTestServer(t *testing.T, failBulk bool) *downloadsTestServer {
+	totals := map[string]uint64{"a": 10, "b": 20, "@scope/c": 30}
+	versions := map[string]string{
+		"a":        `{"package": "a", "downloads": {"1.0.0": 4, "2.0.0": 6}}`,
+		"@scope/c": `{"package": "@scope/c", "downloads": {"1.0.0": 30}}`,
+	}
+
+	s := &downloadsTestServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.paths = append(s.paths, r.URL.Path)
+		s.mu.Unlock()
+
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/downloads/point/last-week/"):
+			names := strings.Split(strings.TrimPrefix(r.URL.Path, "/downloads/point/last-week/"), ",")
+			if len(names) == 1 {
+				total, ok := totals[names[0]]
+				if !ok {
+					http.NotFound(w, r)
+					return
+				}
+
+				fmt.Fprintf(w, `{"downloads": %d, "package": "%s"}`, total, names[0])
+				return
+			}
+
+			if failBulk {
+				http.Error(w, "bulk queries are down", http.StatusServiceUnavailable)
+				return
+			}
+
+			entries := make([]string, len(names))
+			for i, name := range names {
+				entries[i] = fmt.Sprintf(`"%s": null`, name)
+				if total, ok := totals[name]; ok {
+					entries[i] = fmt.Sprintf(`"%s": {"downloads": %d, "package": "%s"}`, name, total, name)
+				}
+			}
+			fmt.Fprintf(w, "{%s}", strings.Join(entries, ","))
+		case strings.HasPrefix(r.URL.Path, "/versions/"):
+			name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/versions/"), "/last-week")
+			if strings.Contains(name, ",") {
+				t.Errorf("per-version downloads were queried in bulk: %s", r.URL.Path)
+			}
+
+			body, ok := versions[name]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+
+			fmt.Fprint(w, body)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *downloadsTestServer) bulkQueries() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for _, p := range s.paths {
+		if strings.HasPrefix(p, "/downloads/point/") && strings.Contains(p, ",") {
+			n++
+		}
+	}
+
+	return n
+}
+
+func TestGetPackagesDownloadTotalsLastWeek(t *testing.T) {
+	tests := []struct {
+		name     string
+		failBulk bool
+	}{
+		{name: "bulk"},
+		{name: "fallback", failBulk: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newDownloadsTestServer(t, test.failBulk)
+			c := New(WithBaseURLs(s.URL, s.URL))
+
+			totals, err := c.GetPackagesDownloadTotalsLastWeek([]string{"a", "b", "a", "@scope/c", "unknown"})
+			if err == nil || !strings.Contains(err.Error(), "unknown") {
+				t.Errorf("error = %v, want an error for the unknown package", err)
+			}
+
+			want := map[string]uint64{"a": 10, "b": 20, "@scope/c": 30}
+			if len(totals) != len(want) {
+				t.Errorf("totals = %v, want %v", totals, want)
+			}
+			for name, total := range want {
+				if totals[name] != total {
+					t.Errorf("total of %s = %d, want %d", name, totals[name], total)
+				}
+			}
+
+			if n := s.bulkQueries(); n != 1 {
+				t.Errorf("sent %d bulk queries, want 1", n)
+			}
+		})
+	}
+}
+
+func TestGetPackagesDownloadsLastWeek(t *testing.T) {
+	s := newDownloadsTestServer(t, false)
+	c := New(WithBaseURLs(s.URL, s.URL))
+
+	downloads, err := c.GetPackagesDownloadsLastWeek([]string{"a", "@scope/c"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if d, ok := downloads["a"].ForVersion("2.0.0"); !ok || d != 6 {
+		t.Errorf("downloads of a@2.0.0 = %d, want 6", d)
+	}
+	if total := downloads["@scope/c"].Total(); total != 30 {
+		t.Errorf("total of @scope/c = %d, want 30", total)
+	}
+}
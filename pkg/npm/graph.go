@@ -0,0 +1,404 @@
+package npm
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// Graph is the dependency graph of an installed tree, reconstructed from its
+// lockfile.
+type Graph struct {
+	Root *GraphNode
+	// Nodes maps the keys of LockedPackages to their nodes, the root is stored
+	// with an empty key
+	Nodes map[string]*GraphNode
+}
+
+type GraphNode struct {
+	// Path is the key of the package in LockedPackages, e.g. "a" or
+	// "a/node_modules/b"
+	Path    string
+	Package PackageJSON
+	// Edges point to the dependencies of the package
+	Edges []*GraphEdge
+	// In are the edges of the packages depending on this package
+	In []*GraphEdge
+	// Missing are dependencies that aren't installed, e.g. optional
+	// dependencies for other platforms
+	Missing []*GraphEdge
+}
+
+// Name returns the name the package is installed as.
+func (n *GraphNode) Name() string {
+	if n.Path == "" {
+		return n.Package.Name
+	}
+
+	if i := strings.LastIndex(n.Path, nodeModulesPrefix); i != -1 {
+		return n.Path[i+len(nodeModulesPrefix):]
+	}
+
+	return n.Path
+}
+
+func (n *GraphNode) String() string {
+	return n.Name() + "@" + n.Package.Version
+}
+
+type GraphEdge struct {
+	From *GraphNode
+	// To is nil for missing dependencies
+	To   *GraphNode
+	Name string
+	Spec string
+	Kind DependencyKind
+}
+
+// NewGraph builds the dependency graph of the lockfile. Dependencies are
+// resolved like Node.js does: a package's own node_modules directory is
+// searched first, then the ones of the packages it is nested in, up to the
+// root. Version 1 lockfiles don't record the dependencies of the root, so
+// without the project's package.json the root depends on every top-level
+// package nothing else depends on.
+func NewGraph(lock *PackageLockJSON) *Graph {
+	g := &Graph{
+		Root:  &GraphNode{Package: lock.Root},
+		Nodes: make(map[string]*GraphNode, len(lock.Packages)+1),
+	}
+	g.Nodes[""] = g.Root
+
+	byLocation := make(map[string]*GraphNode, len(lock.Packages))
+	for path, pkg := range lock.Packages {
+		n := &GraphNode{Path: path, Package: pkg}
+		g.Nodes[path] = n
+		byLocation[locationOf(n)] = n
+	}
+
+	for _, n := range g.sortedNodes() {
+		if n.Package.Link {
+			continue
+		}
+
+		for _, edge := range dependencyEdges(n) {
+			edge.To = resolveDependency(byLocation, locationOf(n), edge.Name)
+			if edge.To == nil {
+				n.Missing = append(n.Missing, edge)
+				continue
+			}
+
+			n.Edges = append(n.Edges, edge)
+			edge.To.In = append(edge.To.In, edge)
+		}
+	}
+
+	if lock.LockfileVersion == 1 && len(g.Root.Edges) == 0 && len(g.Root.Missing) == 0 {
+		for _, n := range g.sortedNodes() {
+			if n == g.Root || len(n.In) > 0 || strings.Contains(n.Path, nodeModulesPrefix) {
+				continue
+			}
+
+			edge := &GraphEdge{From: g.Root, To: n, Name: n.Name(), Spec: n.Package.Version, Kind: n.Package.Kind()}
+			g.Root.Edges = append(g.Root.Edges, edge)
+			n.In = append(n.In, edge)
+		}
+	}
+
+	return g
+}
+
+// sortedNodes returns the nodes sorted by path, so that the graph is built
+// deterministically.
+func (g *Graph) sortedNodes() []*GraphNode {
+	nodes := make([]*GraphNode, 0, len(g.Nodes))
+	for _, n := range g.Nodes {
+		nodes = append(nodes, n)
+	}
+
+	slices.SortFunc(nodes, func(a, b *GraphNode) int {
+		return cmp.Compare(a.Path, b.Path)
+	})
+
+	return nodes
+}
+
+// NodesNamed returns every installed copy of the package, sorted by path.
+func (g *Graph) NodesNamed(name string) []*GraphNode {
+	var nodes []*GraphNode
+	for _, n := range g.sortedNodes() {
+		if n != g.Root && n.Name() == name && !n.Package.Link {
+			nodes = append(nodes, n)
+		}
+	}
+
+	return nodes
+}
+
+func locationOf(n *GraphNode) string {
+	if n.Package.Location != "" || n.Path == "" {
+		return n.Package.Location
+	}
+
+	return nodeModulesPrefix + n.Path
+}
+
+// parentLocation returns the location of the package whose node_modules
+// directory contains the package. Workspaces are nested in the root.
+func parentLocation(location string) string {
+	i := strings.LastIndex(location, nodeModulesPrefix)
+	if i == -1 {
+		return ""
+	}
+
+	return strings.TrimSuffix(location[:i], "/")
+}
+
+func resolveDependency(byLocation map[string]*GraphNode, location, name string) *GraphNode {
+	for {
+		candidate := nodeModulesPrefix + name
+		if location != "" {
+			candidate = location + "/" + candidate
+		}
+
+		if n, ok := byLocation[candidate]; ok {
+			if n.Package.Link {
+				if target, ok := byLocation[n.Package.Resolved]; ok {
+					return target
+				}
+			}
+
+			return n
+		}
+
+		if location == "" {
+			return nil
+		}
+
+		location = parentLocation(location)
+	}
+}
+
+func dependencyEdges(n *GraphNode) []*GraphEdge {
+	kinds := []DependencyKind{DependencyKindProd, DependencyKindPeer, DependencyKindOptional}
+	// Only the root and workspaces get their dev dependencies installed
+	if !strings.HasPrefix(locationOf(n), nodeModulesPrefix) {
+		kinds = append(kinds, DependencyKindDev)
+	}
+
+	// Later kinds take precedence, e.g. dependencies that are also listed as
+	// optional dependencies are optional
+	byName := map[string]*GraphEdge{}
+	for _, kind := range kinds {
+		for _, dep := range n.Package.DependenciesOfKind(kind) {
+			byName[dep.Name] = &GraphEdge{From: n, Name: dep.Name, Spec: dep.RawConstraint, Kind: kind}
+		}
+	}
+
+	edges := make([]*GraphEdge, 0, len(byName))
+	for _, edge := range byName {
+		edges = append(edges, edge)
+	}
+
+	slices.SortFunc(edges, func(a, b *GraphEdge) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	return edges
+}
+
+// NodeSet is a set of nodes in the graph.
+type NodeSet map[*GraphNode]struct{}
+
+func (s NodeSet) Has(n *GraphNode) bool {
+	_, ok := s[n]
+	return ok
+}
+
+// EdgeFilter decides whether an edge is followed during traversals.
+type EdgeFilter func(*GraphEdge) bool
+
+// AllEdges follows every edge.
+func AllEdges(*GraphEdge) bool {
+	return true
+}
+
+// Reachable returns every node that is reachable from the given nodes,
+// including the nodes themselves.
+func (g *Graph) Reachable(from []*GraphNode, follow EdgeFilter) NodeSet {
+	seen := NodeSet{}
+	queue := slices.Clone(from)
+
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+
+		if seen.Has(n) {
+			continue
+		}
+		seen[n] = struct{}{}
+
+		for _, edge := range n.Edges {
+			if follow(edge) && !seen.Has(edge.To) {
+				queue = append(queue, edge.To)
+			}
+		}
+	}
+
+	return seen
+}
+
+// Dominators maps every node that is reachable from the root to its
+// immediate dominator, the closest node every path from the root to it has to
+// go through. The root is its own dominator.
+type Dominators map[*GraphNode]*GraphNode
+
+// Dominators calculates the dominator tree of the graph using the algorithm
+// by Cooper, Harvey and Kennedy.
+// https://www.cs.tufts.edu/comp/150FP/archive/keith-cooper/dom14.pdf
+func (g *Graph) Dominators(follow EdgeFilter) Dominators {
+	// Number the nodes in reverse postorder
+	var postorder []*GraphNode
+	visited := NodeSet{}
+
+	var visit func(n *GraphNode)
+	visit = func(n *GraphNode) {
+		visited[n] = struct{}{}
+		for _, edge := range n.Edges {
+			if follow(edge) && !visited.Has(edge.To) {
+				visit(edge.To)
+			}
+		}
+		postorder = append(postorder, n)
+	}
+	visit(g.Root)
+
+	order := make(map[*GraphNode]int, len(postorder))
+	for i, n := range postorder {
+		order[n] = i
+	}
+
+	idom := Dominators{g.Root: g.Root}
+
+	intersect := func(a, b *GraphNode) *GraphNode {
+		for a != b {
+			for order[a] < order[b] {
+				a = idom[a]
+			}
+			for order[b] < order[a] {
+				b = idom[b]
+			}
+		}
+
+		return a
+	}
+
+	for changed := true; changed; {
+		changed = false
+
+		for i := len(postorder) - 1; i >= 0; i-- {
+			n := postorder[i]
+			if n == g.Root {
+				continue
+			}
+
+			var newIdom *GraphNode
+			for _, edge := range n.In {
+				if !follow(edge) || !visited.Has(edge.From) {
+					continue
+				}
+				if _, ok := idom[edge.From]; !ok {
+					continue
+				}
+
+				if newIdom == nil {
+					newIdom = edge.From
+				} else {
+					newIdom = intersect(edge.From, newIdom)
+				}
+			}
+
+			if newIdom != nil && idom[n] != newIdom {
+				idom[n] = newIdom
+				changed = true
+			}
+		}
+	}
+
+	return idom
+}
+
+// Dominates reports whether every path from the root to b goes through a.
+func (d Dominators) Dominates(a, b *GraphNode) bool {
+	for {
+		if a == b {
+			return true
+		}
+
+		parent, ok := d[b]
+		if !ok || parent == b {
+			return false
+		}
+
+		b = parent
+	}
+}
+
+// Dominated returns the nodes dominated by n, including n itself. Those are
+// the nodes that would become unreachable if n was removed.
+func (d Dominators) Dominated(n *GraphNode) NodeSet {
+	dominated := NodeSet{}
+	for m := range d {
+		if d.Dominates(n, m) {
+			dominated[m] = struct{}{}
+		}
+	}
+
+	return dominated
+}
+
+// PathsTo returns the paths from the root to the node, each starting with the
+// root and ending with the node. Paths don't visit a node twice, and at most
+// limit paths are returned if limit is positive.
+func (g *Graph) PathsTo(target *GraphNode, follow EdgeFilter, limit int) [][]*GraphNode {
+	var paths [][]*GraphNode
+
+	// Walk backwards from the target, so that only edges leading to it are
+	// followed
+	onPath := NodeSet{}
+	reversed := []*GraphNode{}
+
+	var walk func(n *GraphNode) bool
+	walk = func(n *GraphNode) bool {
+		if limit > 0 && len(paths) >= limit {
+			return false
+		}
+
+		reversed = append(reversed, n)
+		onPath[n] = struct{}{}
+		defer func() {
+			reversed = reversed[:len(reversed)-1]
+			delete(onPath, n)
+		}()
+
+		if n == g.Root {
+			path := slices.Clone(reversed)
+			slices.Reverse(path)
+			paths = append(paths, path)
+
+			return true
+		}
+
+		for _, edge := range n.In {
+			if follow(edge) && !onPath.Has(edge.From) {
+				if !walk(edge.From) {
+					return false
+				}
+			}
+		}
+
+		return true
+	}
+	walk(target)
+
+	return paths
+}
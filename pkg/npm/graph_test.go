@@ -0,0 +1,176 @@
+package npm
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewGraph(t *testing.T) {
+	tests := []struct {
+		name     string
+		lockfile string
+		// packageJSON is written next to the lockfile if it's set
+		packageJSON string
+		// edges maps "<from path> > <dependency>" to the path of the resolved
+		// package, or to "missing" for dependencies that aren't installed
+		edges map[string]string
+	}{
+		{
+			name: "hoisted",
+			lockfile: `{
+				"lockfileVersion": 3,
+				"packages": {
+					"": {"name": "project", "dependencies": {"a": "^1.0.0", "b": "^1.0.0"}},
+					"node_modules/a": {"version": "1.0.0", "dependencies": {"c": "^1.0.0"}},
+					"node_modules/b": {"version": "1.0.0", "dependencies": {"c": "^1.0.0"}},
+					"node_modules/c": {"version": "1.0.0"}
+				}
+			}`,
+			edges: map[string]string{
+				" > a":  "a",
+				" > b":  "b",
+				"a > c": "c",
+				"b > c": "c",
+			},
+		},
+		{
+			name: "nested",
+			lockfile: `{
+				"lockfileVersion": 3,
+				"packages": {
+					"": {"name": "project", "dependencies": {"a": "^1.0.0", "b": "^1.0.0"}},
+					"node_modules/a": {"version": "1.0.0", "dependencies": {"c": "^2.0.0"}},
+					"node_modules/a/node_modules/c": {"version": "2.0.0", "dependencies": {"d": "^1.0.0"}},
+					"node_modules/b": {"version": "1.0.0", "dependencies": {"c": "^1.0.0"}},
+					"node_modules/c": {"version": "1.0.0"},
+					"node_modules/d": {"version": "1.0.0"}
+				}
+			}`,
+			edges: map[string]string{
+				" > a":                 "a",
+				" > b":                 "b",
+				"a > c":                "a/node_modules/c",
+				"a/node_modules/c > d": "d",
+				"b > c":                "c",
+			},
+		},
+		{
+			name: "nested in the parent",
+			lockfile: `{
+				"lockfileVersion": 3,
+				"packages": {
+					"": {"name": "project", "dependencies": {"a": "^1.0.0"}},
+					"node_modules/a": {"version": "1.0.0", "dependencies": {"b": "^1.0.0", "c": "^2.0.0"}},
+					"node_modules/a/node_modules/b": {"version": "1.0.0", "dependencies": {"c": "^2.0.0"}},
+					"node_modules/a/node_modules/c": {"version": "2.0.0"}
+				}
+			}`,
+			edges: map[string]string{
+				" > a":                 "a",
+				"a > b":                "a/node_modules/b",
+				"a > c":                "a/node_modules/c",
+				"a/node_modules/b > c": "a/node_modules/c",
+			},
+		},
+		{
+			name: "missing optional dependency",
+			lockfile: `{
+				"lockfileVersion": 3,
+				"packages": {
+					"": {"name": "project", "dependencies": {"a": "^1.0.0"}},
+					"node_modules/a": {"version": "1.0.0", "optionalDependencies": {"a-darwin": "1.0.0"}}
+				}
+			}`,
+			edges: map[string]string{
+				" > a":         "a",
+				"a > a-darwin": "missing",
+			},
+		},
+		{
+			name: "v1 root",
+			lockfile: `{
+				"name": "project",
+				"lockfileVersion": 1,
+				"dependencies": {
+					"a": {
+						"version": "1.0.0",
+						"requires": {"b": "^1.0.0"},
+						"dependencies": {
+							"b": {"version": "1.0.0"}
+						}
+					},
+					"c": {"version": "1.0.0"}
+				}
+			}`,
+			edges: map[string]string{
+				" > a":  "a",
+				" > c":  "c",
+				"a > b": "a/node_modules/b",
+			},
+		},
+		{
+			name: "v1 root with package.json",
+			lockfile: `{
+				"name": "project",
+				"lockfileVersion": 1,
+				"dependencies": {
+					"a": {"version": "1.0.0", "requires": {"c": "^1.0.0"}},
+					"c": {"version": "1.0.0"},
+					"extraneous": {"version": "1.0.0"}
+				}
+			}`,
+			packageJSON: `{
+				"name": "project",
+				"dependencies": {"a": "^1.0.0"},
+				"optionalDependencies": {"d": "^1.0.0"}
+			}`,
+			edges: map[string]string{
+				" > a":  "a",
+				" > d":  "missing",
+				"a > c": "c",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := writeTestFile(t, "package-lock.json", test.lockfile)
+			if test.packageJSON != "" {
+				if err := os.WriteFile(filepath.Join(filepath.Dir(path), "package.json"), []byte(test.packageJSON), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			lock, err := ParsePackageLockJSON(path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			g := NewGraph(lock)
+
+			edges := map[string]string{}
+			for path, n := range g.Nodes {
+				for _, e := range n.Edges {
+					edges[path+" > "+e.Name] = e.To.Path
+				}
+				for _, e := range n.Missing {
+					edges[path+" > "+e.Name] = "missing"
+				}
+			}
+
+			if !maps.Equal(edges, test.edges) {
+				t.Errorf("edges = %v, want %v", edges, test.edges)
+			}
+
+			for path, n := range g.Nodes {
+				for _, in := range n.In {
+					if in.To != n {
+						t.Errorf("incoming edge of %s from %s points to %s", path, in.From.Path, in.To.Path)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,372 @@
+package npm
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// maxConcurrentDownloads limits the tarballs that are downloaded at once
+const maxConcurrentDownloads = 16
+
+// publicRegistryBases are the hosts tarball URLs in lockfiles point to, which
+// are rewritten to the configured registry
+var publicRegistryBases = []string{NPMRegistryBase, "https://registry.npmjs.org"}
+
+var ErrIntegrityMismatch = errors.New("integrity mismatch")
+
+// Installer installs the packages of a lockfile by downloading and extracting
+// their tarballs, without npm. Install scripts aren't run.
+type Installer struct {
+	client *Client
+
+	cacheDir      string
+	readOnlyCache bool
+	platform      Platform
+}
+
+type InstallerOpt func(*Installer)
+
+// WithTarballCache stores downloaded tarballs in the directory, keyed by their
+// integrity. A read-only cache is only read from.
+func WithTarballCache(dir string, readOnly bool) InstallerOpt {
+	return func(i *Installer) {
+		i.cacheDir = dir
+		i.readOnlyCache = readOnly
+	}
+}
+
+// WithPlatform sets the platform optional dependencies are installed for.
+func WithPlatform(p Platform) InstallerOpt {
+	return func(i *Installer) {
+		i.platform = p
+	}
+}
+
+func (c *Client) NewInstaller(opts ...InstallerOpt) *Installer {
+	i := &Installer{
+		client:   c,
+		platform: DefaultPlatform,
+	}
+
+	for _, opt := range opts {
+		opt(i)
+	}
+
+	return i
+}
+
+// Install extracts every package of the lockfile into the node_modules layout
+// it describes below dir. Links and packages without a tarball, e.g. bundled
+// dependencies, are skipped. Missing licenses are filled in from the extracted
+// package.json, like npm records them.
+func (i *Installer) Install(lock *PackageLockJSON, dir string) error {
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		errs     []error
+		licenses = map[string]License{}
+		sem      = make(chan struct{}, maxConcurrentDownloads)
+	)
+
+	for name, pkg := range lock.Packages {
+		l := log.With().Str("package", name).Logger()
+
+		if pkg.Link || pkg.Resolved == "" {
+			l.Debug().Msg("Skipping package without a tarball")
+			continue
+		}
+
+		if (pkg.Optional || pkg.DevOptional) && !i.platform.Supports(pkg) {
+			l.Debug().Str("platform", i.platform.String()).Msg("Skipping optional dependency for other platforms")
+			continue
+		}
+
+		location := pkg.Location
+		if location == "" {
+			location = nodeModulesPrefix + name
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			dest := filepath.Join(dir, filepath.FromSlash(location))
+			if err := i.installPackage(pkg, dest); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("failed to install \"%s\": %w", name, err))
+				mu.Unlock()
+				return
+			}
+
+			if pkg.License == "" {
+				if license := readInstalledLicense(dest); license != "" {
+					mu.Lock()
+					licenses[name] = license
+					mu.Unlock()
+				}
+			}
+
+			l.Trace().Msg("Installed package")
+		}()
+	}
+
+	wg.Wait()
+
+	for name, license := range licenses {
+		pkg := lock.Packages[name]
+		pkg.License = license
+		lock.Packages[name] = pkg
+	}
+
+	return errors.Join(errs...)
+}
+
+// readInstalledLicense reads the license of an extracted package, empty if it
+// has none or its package.json can't be read.
+func readInstalledLicense(dir string) License {
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return ""
+	}
+
+	var manifest struct {
+		License License `json:"license"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return ""
+	}
+
+	return manifest.License
+}
+
+func (i *Installer) installPackage(pkg PackageJSON, dest string) error {
+	tarball, err := i.fetchTarball(pkg)
+	if err != nil {
+		return err
+	}
+
+	return extractTarball(tarball, dest)
+}
+
+func (i *Installer) fetchTarball(pkg PackageJSON) ([]byte, error) {
+	cachePath := i.cachePath(pkg.Integrity)
+	if cachePath != "" {
+		if tarball, err := os.ReadFile(cachePath); err == nil {
+			if err := checkIntegrity(tarball, pkg.Integrity); err == nil {
+				return tarball, nil
+			}
+
+			log.Warn().Str("path", cachePath).Msg("Ignoring corrupted tarball in cache")
+		}
+	}
+
+	resp, err := i.client.c.Get(i.tarballURL(pkg.Resolved))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: %s", pkg.Resolved, resp.Status)
+	}
+
+	tarball, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkIntegrity(tarball, pkg.Integrity); err != nil {
+		return nil, err
+	}
+
+	if cachePath != "" && !i.readOnlyCache {
+		if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err != nil {
+			log.Warn().Err(err).Msg("Failed to create tarball cache")
+		} else if err := os.WriteFile(cachePath, tarball, 0644); err != nil {
+			log.Warn().Err(err).Str("path", cachePath).Msg("Failed to cache tarball")
+		}
+	}
+
+	return tarball, nil
+}
+
+// tarballURL points tarballs of the public registry to the configured one.
+func (i *Installer) tarballURL(resolved string) string {
+	for _, base := range publicRegistryBases {
+		if rest, ok := strings.CutPrefix(resolved, base+"/"); ok {
+			return i.client.registryBase + "/" + rest
+		}
+	}
+
+	return resolved
+}
+
+func (i *Installer) cachePath(integrity string) string {
+	if i.cacheDir == "" || integrity == "" {
+		return ""
+	}
+
+	algorithm, digest, ok := strongestHash(integrity)
+	if !ok {
+		return ""
+	}
+
+	// Base64 digests can contain "/", which can't be used in file names
+	name := algorithm + "-" + base64.RawURLEncoding.EncodeToString(digest)
+
+	return filepath.Join(i.cacheDir, "_tarballs", name+".tgz")
+}
+
+// hashPriorities lists the supported hash algorithms of integrity strings,
+// from the strongest to the weakest
+var hashPriorities = []string{"sha512", "sha384", "sha256", "sha1"}
+
+// strongestHash picks the strongest supported hash of a subresource integrity
+// string, which can contain several hashes separated by spaces.
+func strongestHash(integrity string) (string, []byte, bool) {
+	hashes := map[string][]byte{}
+	for _, entry := range strings.Fields(integrity) {
+		algorithm, encoded, ok := strings.Cut(entry, "-")
+		if !ok {
+			continue
+		}
+
+		// Options after the digest aren't used by npm
+		encoded, _, _ = strings.Cut(encoded, "?")
+
+		digest, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			continue
+		}
+
+		hashes[algorithm] = digest
+	}
+
+	for _, algorithm := range hashPriorities {
+		if digest, ok := hashes[algorithm]; ok {
+			return algorithm, digest, true
+		}
+	}
+
+	return "", nil, false
+}
+
+// checkIntegrity verifies the tarball against its subresource integrity
+// string. Tarballs without one, e.g. from git repositories, can't be checked.
+func checkIntegrity(tarball []byte, integrity string) error {
+	if integrity == "" {
+		return nil
+	}
+
+	algorithm, expected, ok := strongestHash(integrity)
+	if !ok {
+		return fmt.Errorf("%w: unsupported integrity \"%s\"", ErrIntegrityMismatch, integrity)
+	}
+
+	var h hash.Hash
+	switch algorithm {
+	case "sha512":
+		h = sha512.New()
+	case "sha384":
+		h = sha512.New384()
+	case "sha256":
+		h = sha256.New()
+	case "sha1":
+		h = sha1.New()
+	}
+	h.Write(tarball)
+
+	if !bytes.Equal(h.Sum(nil), expected) {
+		return fmt.Errorf("%w: expected %s", ErrIntegrityMismatch, integrity)
+	}
+
+	return nil
+}
+
+// extractTarball extracts the files of a package tarball into dest. The first
+// directory of every path is stripped, it is "package" for most packages.
+// Like npm, only files and directories are extracted.
+func extractTarball(tarball []byte, dest string) error {
+	gz, err := gzip.NewReader(bytes.NewReader(tarball))
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return err
+	}
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		name := path.Clean("/" + header.Name)
+		_, name, _ = strings.Cut(strings.TrimPrefix(name, "/"), "/")
+		if name == "" {
+			continue
+		}
+
+		target := filepath.Join(dest, filepath.FromSlash(name))
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeTarballFile(tr, target, header.FileInfo().Mode()); err != nil {
+				return err
+			}
+		default:
+			log.Trace().Str("file", header.Name).Msg("Skipping unsupported tarball entry")
+		}
+	}
+}
+
+func writeTarballFile(r io.Reader, target string, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	// npm makes every file readable and keeps the executable bit
+	perm := os.FileMode(0644)
+	if mode&0111 != 0 {
+		perm = 0755
+	}
+
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(f, r)
+	return err
+}
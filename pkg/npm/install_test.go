@@ -0,0 +1,243 @@
+package npm
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+type tarballTestEntry struct {
+	Name    string
+	Content string
+	Type    byte
+}
+
+func testTarball(t *testing.T, entries []tarballTestEntry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		typ := e.Type
+		if typ == 0 {
+			typ = tar.TypeReg
+		}
+
+		header := &tar.Header{Name: e.Name, Typeflag: typ, Mode: 0644, Size: int64(len(e.Content))}
+		if typ == tar.TypeSymlink {
+			header.Linkname = e.Content
+			header.Size = 0
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if typ == tar.TypeReg {
+			if _, err := tw.Write([]byte(e.Content)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func sha512Integrity(data []byte) string {
+	sum := sha512.Sum512(data)
+	return "sha512-" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// listFiles returns the files below dir, relative to it
+func listFiles(t *testing.T, dir string) []string {
+	t.Helper()
+
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		files = append(files, filepath.ToSlash(rel))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(files)
+
+	return files
+}
+
+func TestExtractTarball(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []tarballTestEntry
+		// files are below the parent of the destination, so that files
+		// written outside of it show up
+		files []string
+	}{
+		{
+			name: "package",
+			entries: []tarballTestEntry{
+				{Name: "package/package.json", Content: "{}"},
+				{Name: "package/lib/index.js", Content: "module.exports = 1"},
+			},
+			files: []string{"pkg/lib/index.js", "pkg/package.json"},
+		},
+		{
+			name: "other first directory",
+			entries: []tarballTestEntry{
+				{Name: "node/package.json", Content: "{}"},
+			},
+			files: []string{"pkg/package.json"},
+		},
+		{
+			name: "parent directory",
+			entries: []tarballTestEntry{
+				{Name: "package/package.json", Content: "{}"},
+				{Name: "package/../../evil.js", Content: "evil"},
+				{Name: "../../lib/evil.js", Content: "evil"},
+			},
+			files: []string{"pkg/evil.js", "pkg/package.json"},
+		},
+		{
+			name: "absolute path",
+			entries: []tarballTestEntry{
+				{Name: "package/package.json", Content: "{}"},
+				{Name: "/tmp/evil.js", Content: "evil"},
+			},
+			files: []string{"pkg/evil.js", "pkg/package.json"},
+		},
+		{
+			name: "symlink",
+			entries: []tarballTestEntry{
+				{Name: "package/package.json", Content: "{}"},
+				{Name: "package/link", Content: "../../evil.js", Type: tar.TypeSymlink},
+			},
+			files: []string{"pkg/package.json"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			parent := t.TempDir()
+			if err := extractTarball(testTarball(t, test.entries), filepath.Join(parent, "pkg")); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if files := listFiles(t, parent); !slices.Equal(files, test.files) {
+				t.Errorf("files = %v, want %v", files, test.files)
+			}
+		})
+	}
+}
+
+func TestInstall(t *testing.T) {
+	tarball := testTarball(t, []tarballTestEntry{
+		{Name: "package/package.json", Content: `{"name": "a", "version": "1.0.0", "license": "MIT"}`},
+	})
+
+	tests := []struct {
+		name      string
+		integrity string
+		wantErr   error
+		files     []string
+	}{
+		{
+			name:      "matching integrity",
+			integrity: sha512Integrity(tarball),
+			files:     []string{"node_modules/a/package.json"},
+		},
+		{
+			name:      "sha512 mismatch",
+			integrity: sha512Integrity([]byte("another tarball")),
+			wantErr:   ErrIntegrityMismatch,
+		},
+		{
+			name:      "strongest hash mismatches",
+			integrity: "sha1-" + base64.StdEncoding.EncodeToString(make([]byte, 20)) + " " + sha512Integrity([]byte("another tarball")),
+			wantErr:   ErrIntegrityMismatch,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write(tarball)
+			}))
+			defer s.Close()
+
+			lock := &PackageLockJSON{
+				LockfileVersion: 3,
+				Packages: LockedPackages{
+					"a": {Name: "a", Version: "1.0.0", Location: "node_modules/a", Resolved: s.URL + "/a/-/a-1.0.0.tgz", Integrity: test.integrity},
+				},
+			}
+
+			dir := t.TempDir()
+			err := New(WithBaseURLs(s.URL, s.URL)).NewInstaller().Install(lock, dir)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("error = %v, want %v", err, test.wantErr)
+			}
+
+			if files := listFiles(t, dir); !slices.Equal(files, test.files) {
+				t.Errorf("files = %v, want %v", files, test.files)
+			}
+
+			if test.wantErr == nil && lock.Packages["a"].License != "MIT" {
+				t.Errorf("license = %q, want the license of the extracted package.json", lock.Packages["a"].License)
+			}
+		})
+	}
+}
+
+func TestFetchTarballReplacesCorruptedCache(t *testing.T) {
+	tarball := testTarball(t, []tarballTestEntry{{Name: "package/package.json", Content: "{}"}})
+	integrity := sha512Integrity(tarball)
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(tarball)
+	}))
+	defer s.Close()
+
+	cache := t.TempDir()
+	i := New(WithBaseURLs(s.URL, s.URL)).NewInstaller(WithTarballCache(cache, false))
+
+	cachePath := i.cachePath(integrity)
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cachePath, []byte("corrupted"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := i.fetchTarball(PackageJSON{Resolved: s.URL + "/a/-/a-1.0.0.tgz", Integrity: integrity})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(got, tarball) {
+		t.Error("fetched tarball isn't the downloaded one")
+	}
+
+	cached, err := os.ReadFile(cachePath)
+	if err != nil || !bytes.Equal(cached, tarball) {
+		t.Error("corrupted tarball in the cache wasn't replaced")
+	}
+}
@@ -0,0 +1,202 @@
+package npm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// The helpers in this file edit members of JSON objects in place, so that
+// everything except the edited members stays byte-for-byte the same.
+
+type jsonMember struct {
+	Key        string
+	KeyStart   int
+	KeyEnd     int
+	ValueStart int
+	ValueEnd   int
+}
+
+type jsonObject struct {
+	// Start is the offset of the opening brace, End the offset after the
+	// closing brace
+	Start   int
+	End     int
+	Members []jsonMember
+}
+
+func (o jsonObject) member(key string) (int, bool) {
+	for i, m := range o.Members {
+		if m.Key == key {
+			return i, true
+		}
+	}
+
+	return -1, false
+}
+
+func parseJSONObject(data []byte, start int) (jsonObject, error) {
+	i := skipJSONWhitespace(data, start)
+	if i >= len(data) || data[i] != '{' {
+		return jsonObject{}, fmt.Errorf("expected object at offset %d", i)
+	}
+
+	o := jsonObject{Start: i}
+	i++
+
+	for {
+		i = skipJSONWhitespace(data, i)
+		if i >= len(data) {
+			return jsonObject{}, fmt.Errorf("unexpected end of object starting at offset %d", o.Start)
+		}
+
+		if data[i] == '}' {
+			o.End = i + 1
+			return o, nil
+		}
+
+		if len(o.Members) > 0 {
+			if data[i] != ',' {
+				return jsonObject{}, fmt.Errorf("expected ',' at offset %d", i)
+			}
+			i = skipJSONWhitespace(data, i+1)
+		}
+
+		m := jsonMember{KeyStart: i}
+
+		end, err := skipJSONValue(data, i)
+		if err != nil {
+			return jsonObject{}, err
+		}
+		if err := json.Unmarshal(data[i:end], &m.Key); err != nil {
+			return jsonObject{}, fmt.Errorf("invalid key at offset %d: %w", i, err)
+		}
+		m.KeyEnd = end
+
+		i = skipJSONWhitespace(data, end)
+		if i >= len(data) || data[i] != ':' {
+			return jsonObject{}, fmt.Errorf("expected ':' at offset %d", i)
+		}
+
+		m.ValueStart = skipJSONWhitespace(data, i+1)
+		m.ValueEnd, err = skipJSONValue(data, m.ValueStart)
+		if err != nil {
+			return jsonObject{}, err
+		}
+
+		o.Members = append(o.Members, m)
+		i = m.ValueEnd
+	}
+}
+
+func skipJSONValue(data []byte, start int) (int, error) {
+	dec := json.NewDecoder(bytes.NewReader(data[start:]))
+
+	var v json.RawMessage
+	if err := dec.Decode(&v); err != nil {
+		return 0, fmt.Errorf("invalid value at offset %d: %w", start, err)
+	}
+
+	return start + int(dec.InputOffset()), nil
+}
+
+func skipJSONWhitespace(data []byte, i int) int {
+	for i < len(data) && (data[i] == ' ' || data[i] == '\t' || data[i] == '\n' || data[i] == '\r') {
+		i++
+	}
+
+	return i
+}
+
+// removeJSONMember removes the member with the given key from the object
+// starting at start.
+func removeJSONMember(data []byte, start int, key string) ([]byte, bool, error) {
+	o, err := parseJSONObject(data, start)
+	if err != nil {
+		return nil, false, err
+	}
+
+	idx, ok := o.member(key)
+	if !ok {
+		return data, false, nil
+	}
+
+	m := o.Members[idx]
+
+	var from, to int
+	switch {
+	case len(o.Members) == 1:
+		from, to = o.Start+1, o.End-1
+	case idx < len(o.Members)-1:
+		// Take over the indentation of the removed member
+		from, to = m.KeyStart, o.Members[idx+1].KeyStart
+	default:
+		from, to = o.Members[idx-1].ValueEnd, m.ValueEnd
+	}
+
+	return splice(data, from, to, nil), true, nil
+}
+
+// setJSONMember replaces the value of the member with the given key in the
+// object starting at start, or appends the member if it doesn't exist yet.
+func setJSONMember(data []byte, start int, key string, value []byte) ([]byte, error) {
+	o, err := parseJSONObject(data, start)
+	if err != nil {
+		return nil, err
+	}
+
+	if idx, ok := o.member(key); ok {
+		m := o.Members[idx]
+		return splice(data, m.ValueStart, m.ValueEnd, value), nil
+	}
+
+	encodedKey, err := json.Marshal(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(o.Members) == 0 {
+		member := append(append(encodedKey, ": "...), value...)
+		return splice(data, o.Start+1, o.End-1, member), nil
+	}
+
+	// Copy the formatting of the last member
+	last := o.Members[len(o.Members)-1]
+	indentStart := last.KeyStart
+	for indentStart > o.Start+1 && bytes.ContainsRune([]byte(" \t\r\n"), rune(data[indentStart-1])) {
+		indentStart--
+	}
+
+	member := []byte{','}
+	member = append(member, data[indentStart:last.KeyStart]...)
+	member = append(member, encodedKey...)
+	member = append(member, data[last.KeyEnd:last.ValueStart]...)
+	member = append(member, value...)
+
+	return splice(data, last.ValueEnd, last.ValueEnd, member), nil
+}
+
+// memberValueStart returns the offset of the value of the member with the
+// given key in the object starting at start.
+func memberValueStart(data []byte, start int, key string) (int, bool, error) {
+	o, err := parseJSONObject(data, start)
+	if err != nil {
+		return 0, false, err
+	}
+
+	idx, ok := o.member(key)
+	if !ok {
+		return 0, false, nil
+	}
+
+	return o.Members[idx].ValueStart, true, nil
+}
+
+func splice(data []byte, from, to int, insert []byte) []byte {
+	out := make([]byte, 0, len(data)-(to-from)+len(insert))
+	out = append(out, data[:from]...)
+	out = append(out, insert...)
+	out = append(out, data[to:]...)
+
+	return out
+}
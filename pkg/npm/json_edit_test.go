@@ -0,0 +1,216 @@
+package npm
+
+import (
+	"testing"
+)
+
+func TestSetJSONMember(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		key   string
+		value string
+		want  string
+	}{
+		{
+			name:  "empty object",
+			doc:   `{}`,
+			key:   "a",
+			value: `"1"`,
+			want:  `{"a": "1"}`,
+		},
+		{
+			name:  "empty object with whitespace",
+			doc:   "{\n}",
+			key:   "a",
+			value: `"1"`,
+			want:  `{"a": "1"}`,
+		},
+		{
+			name:  "replace last member",
+			doc:   `{"a": "1", "b": "2"}`,
+			key:   "b",
+			value: `"3"`,
+			want:  `{"a": "1", "b": "3"}`,
+		},
+		{
+			name:  "replace object value",
+			doc:   `{"a": {"nested": true}, "b": 1}`,
+			key:   "a",
+			value: `"1"`,
+			want:  `{"a": "1", "b": 1}`,
+		},
+		{
+			name:  "append with indentation",
+			doc:   "{\n    \"a\": \"1\",\n    \"b\": \"2\"\n}",
+			key:   "c",
+			value: `"3"`,
+			want:  "{\n    \"a\": \"1\",\n    \"b\": \"2\",\n    \"c\": \"3\"\n}",
+		},
+		{
+			name:  "append with CRLF and tabs",
+			doc:   "{\r\n\t\"a\": \"1\"\r\n}\r\n",
+			key:   "b",
+			value: `"2"`,
+			want:  "{\r\n\t\"a\": \"1\",\r\n\t\"b\": \"2\"\r\n}\r\n",
+		},
+		{
+			name:  "append without spaces around the colon",
+			doc:   `{"a":"1"}`,
+			key:   "b",
+			value: `"2"`,
+			want:  `{"a":"1","b":"2"}`,
+		},
+		{
+			name:  "escaped key",
+			doc:   `{"a\"b": "1", "\u0063": "2"}`,
+			key:   "c",
+			value: `"3"`,
+			want:  `{"a\"b": "1", "\u0063": "3"}`,
+		},
+		{
+			name:  "new escaped key",
+			doc:   `{"a": "1", "b": "2"}`,
+			key:   `c"d`,
+			value: `"3"`,
+			want:  `{"a": "1", "b": "2", "c\"d": "3"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := setJSONMember([]byte(test.doc), 0, test.key, []byte(test.value))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if string(got) != test.want {
+				t.Errorf("document = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRemoveJSONMember(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		key     string
+		want    string
+		removed bool
+	}{
+		{
+			name: "empty object",
+			doc:  `{}`,
+			key:  "a",
+			want: `{}`,
+		},
+		{
+			name:    "only member",
+			doc:     "{\n  \"a\": 1\n}",
+			key:     "a",
+			want:    "{}",
+			removed: true,
+		},
+		{
+			name:    "first member",
+			doc:     "{\n  \"a\": 1,\n  \"b\": 2\n}",
+			key:     "a",
+			want:    "{\n  \"b\": 2\n}",
+			removed: true,
+		},
+		{
+			name:    "last member",
+			doc:     "{\n  \"a\": 1,\n  \"b\": 2\n}",
+			key:     "b",
+			want:    "{\n  \"a\": 1\n}",
+			removed: true,
+		},
+		{
+			name:    "last member with CRLF",
+			doc:     "{\r\n\t\"a\": 1,\r\n\t\"b\": 2\r\n}",
+			key:     "b",
+			want:    "{\r\n\t\"a\": 1\r\n}",
+			removed: true,
+		},
+		{
+			name:    "object value",
+			doc:     `{"a": {"b": {"c": 1}}, "d": [1, {"e": 2}]}`,
+			key:     "a",
+			want:    `{"d": [1, {"e": 2}]}`,
+			removed: true,
+		},
+		{
+			name:    "escaped key",
+			doc:     `{"a": 1, "\u0062": 2}`,
+			key:     "b",
+			want:    `{"a": 1}`,
+			removed: true,
+		},
+		{
+			name: "missing member",
+			doc:  `{"a": 1}`,
+			key:  "b",
+			want: `{"a": 1}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, removed, err := removeJSONMember([]byte(test.doc), 0, test.key)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if string(got) != test.want || removed != test.removed {
+				t.Errorf("document = %q (removed %v), want %q (removed %v)", got, removed, test.want, test.removed)
+			}
+		})
+	}
+}
+
+func TestEditNestedJSONObject(t *testing.T) {
+	doc := "{\n  \"name\": \"a\",\n  \"dependencies\": {\n    \"b\": \"^1.0.0\",\n    \"c\": \"^2.0.0\"\n  },\n  \"z\": null\n}\n"
+
+	start, ok, err := memberValueStart([]byte(doc), 0, "dependencies")
+	if err != nil || !ok {
+		t.Fatalf("dependencies not found: %v", err)
+	}
+
+	got, err := setJSONMember([]byte(doc), start, "d", []byte(`"^3.0.0"`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _, err = removeJSONMember(got, start, "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "{\n  \"name\": \"a\",\n  \"dependencies\": {\n    \"c\": \"^2.0.0\",\n    \"d\": \"^3.0.0\"\n  },\n  \"z\": null\n}\n"
+	if string(got) != want {
+		t.Errorf("document = %q, want %q", got, want)
+	}
+}
+
+func TestEditablePackageJSONRoundTrip(t *testing.T) {
+	docs := []string{
+		"{\n  \"name\": \"a\",\n  \"version\": \"1.0.0\",\n  \"custom\": {\"x\": [1, 2.50, \"\\u00e9\"]}\n}\n",
+		"{\r\n\t\"name\":\"a\",\r\n\t\"dependencies\":{\"b\":\"*\"}\r\n}",
+		`{"name":"a","keywords":[],"bin":{"a":"./a.js"}}`,
+	}
+
+	for _, doc := range docs {
+		p, err := ParseEditablePackageJSON(writeTestFile(t, "package.json", doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := p.Clone().JSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != doc {
+			t.Errorf("document = %q, want %q", got, doc)
+		}
+	}
+}
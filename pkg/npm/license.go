@@ -0,0 +1,162 @@
+package npm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrInvalidLicenseExpression = errors.New("invalid license expression")
+
+const (
+	LicenseOperatorAnd = "AND"
+	LicenseOperatorOr  = "OR"
+
+	licenseOperatorWith = "WITH"
+)
+
+// LicenseExpression is a parsed SPDX license expression. Either ID is set for
+// a single license, or Operator combines the operands.
+type LicenseExpression struct {
+	// ID is the license identifier, including the exception for expressions
+	// like "GPL-2.0-only WITH Classpath-exception-2.0"
+	ID       string
+	Operator string
+	Operands []LicenseExpression
+}
+
+// IDs returns every license identifier of the expression without exceptions.
+func (e LicenseExpression) IDs() []string {
+	if e.Operator == "" {
+		id, _, _ := strings.Cut(e.ID, " "+licenseOperatorWith+" ")
+		return []string{id}
+	}
+
+	var ids []string
+	for _, o := range e.Operands {
+		ids = append(ids, o.IDs()...)
+	}
+
+	return ids
+}
+
+// Parse parses the license as an SPDX expression. "AND" binds tighter than
+// "OR", operators are case-insensitive.
+func (l License) Parse() (LicenseExpression, error) {
+	p := licenseParser{tokens: tokenizeLicense(string(l))}
+	if len(p.tokens) == 0 {
+		return LicenseExpression{}, fmt.Errorf("%w: empty expression", ErrInvalidLicenseExpression)
+	}
+
+	e, err := p.parseOr()
+	if err != nil {
+		return LicenseExpression{}, err
+	}
+
+	if p.pos != len(p.tokens) {
+		return LicenseExpression{}, fmt.Errorf("%w: unexpected \"%s\"", ErrInvalidLicenseExpression, p.tokens[p.pos])
+	}
+
+	return e, nil
+}
+
+func tokenizeLicense(s string) []string {
+	s = strings.ReplaceAll(s, "(", " ( ")
+	s = strings.ReplaceAll(s, ")", " ) ")
+
+	return strings.Fields(s)
+}
+
+type licenseParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *licenseParser) peekOperator(operator string) bool {
+	return p.pos < len(p.tokens) && strings.EqualFold(p.tokens[p.pos], operator)
+}
+
+func (p *licenseParser) parseOr() (LicenseExpression, error) {
+	return p.parseBinary(LicenseOperatorOr, p.parseAnd)
+}
+
+func (p *licenseParser) parseAnd() (LicenseExpression, error) {
+	return p.parseBinary(LicenseOperatorAnd, p.parseWith)
+}
+
+func (p *licenseParser) parseBinary(operator string, operand func() (LicenseExpression, error)) (LicenseExpression, error) {
+	first, err := operand()
+	if err != nil {
+		return LicenseExpression{}, err
+	}
+
+	operands := []LicenseExpression{first}
+	for p.peekOperator(operator) {
+		p.pos++
+
+		next, err := operand()
+		if err != nil {
+			return LicenseExpression{}, err
+		}
+
+		operands = append(operands, next)
+	}
+
+	if len(operands) == 1 {
+		return first, nil
+	}
+
+	return LicenseExpression{Operator: operator, Operands: operands}, nil
+}
+
+func (p *licenseParser) parseWith() (LicenseExpression, error) {
+	e, err := p.parseAtom()
+	if err != nil {
+		return LicenseExpression{}, err
+	}
+
+	if !p.peekOperator(licenseOperatorWith) {
+		return e, nil
+	}
+	p.pos++
+
+	if e.Operator != "" || p.pos == len(p.tokens) {
+		return LicenseExpression{}, fmt.Errorf("%w: exceptions can only follow a license", ErrInvalidLicenseExpression)
+	}
+
+	e.ID += " " + licenseOperatorWith + " " + p.tokens[p.pos]
+	p.pos++
+
+	return e, nil
+}
+
+func (p *licenseParser) parseAtom() (LicenseExpression, error) {
+	if p.pos == len(p.tokens) {
+		return LicenseExpression{}, fmt.Errorf("%w: unexpected end", ErrInvalidLicenseExpression)
+	}
+
+	token := p.tokens[p.pos]
+	p.pos++
+
+	switch {
+	case token == "(":
+		e, err := p.parseOr()
+		if err != nil {
+			return LicenseExpression{}, err
+		}
+
+		if p.pos == len(p.tokens) || p.tokens[p.pos] != ")" {
+			return LicenseExpression{}, fmt.Errorf("%w: missing \")\"", ErrInvalidLicenseExpression)
+		}
+		p.pos++
+
+		return e, nil
+	case token == ")",
+		strings.EqualFold(token, LicenseOperatorAnd),
+		strings.EqualFold(token, LicenseOperatorOr),
+		strings.EqualFold(token, licenseOperatorWith):
+		return LicenseExpression{}, fmt.Errorf("%w: unexpected \"%s\"", ErrInvalidLicenseExpression, token)
+	}
+
+	return LicenseExpression{ID: token}, nil
+}
@@ -0,0 +1,101 @@
+package npm
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// formatLicenseExpression writes the expression with parentheses around every
+// combination, so that the tests show how it was grouped
+func formatLicenseExpression(e LicenseExpression) string {
+	if e.Operator == "" {
+		return e.ID
+	}
+
+	operands := make([]string, len(e.Operands))
+	for i, o := range e.Operands {
+		operands[i] = formatLicenseExpression(o)
+	}
+
+	return "(" + strings.Join(operands, " "+e.Operator+" ") + ")"
+}
+
+func TestLicenseParse(t *testing.T) {
+	tests := []struct {
+		license string
+		// want is the grouped expression, empty if it's invalid
+		want string
+		ids  []string
+	}{
+		{license: "MIT", want: "MIT", ids: []string{"MIT"}},
+		{license: "MIT OR Apache-2.0", want: "(MIT OR Apache-2.0)", ids: []string{"MIT", "Apache-2.0"}},
+		{license: "MIT AND ISC AND BSD-3-Clause", want: "(MIT AND ISC AND BSD-3-Clause)", ids: []string{"MIT", "ISC", "BSD-3-Clause"}},
+		{license: "MIT OR ISC AND GPL-3.0-only", want: "(MIT OR (ISC AND GPL-3.0-only))", ids: []string{"MIT", "ISC", "GPL-3.0-only"}},
+		{license: "(MIT OR ISC) AND GPL-3.0-only", want: "((MIT OR ISC) AND GPL-3.0-only)", ids: []string{"MIT", "ISC", "GPL-3.0-only"}},
+		{license: "((MIT))", want: "MIT", ids: []string{"MIT"}},
+		{license: "mit or apache-2.0", want: "(mit OR apache-2.0)", ids: []string{"mit", "apache-2.0"}},
+		{license: "GPL-2.0-only WITH Classpath-exception-2.0", want: "GPL-2.0-only WITH Classpath-exception-2.0", ids: []string{"GPL-2.0-only"}},
+		{license: "MIT OR GPL-2.0-or-later WITH Bison-exception-2.2", want: "(MIT OR GPL-2.0-or-later WITH Bison-exception-2.2)", ids: []string{"MIT", "GPL-2.0-or-later"}},
+		{license: ""},
+		{license: "MIT OR"},
+		{license: "OR MIT"},
+		{license: "(MIT"},
+		{license: "MIT)"},
+		{license: "GPL-2.0-only WITH"},
+		{license: "(MIT OR ISC) WITH Classpath-exception-2.0"},
+		// npm's way of referring to a custom license file isn't an expression
+		{license: "SEE LICENSE IN LICENSE.md"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.license, func(t *testing.T) {
+			e, err := License(test.license).Parse()
+			if test.want == "" {
+				if !errors.Is(err, ErrInvalidLicenseExpression) {
+					t.Errorf("error = %v, want %v", err, ErrInvalidLicenseExpression)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := formatLicenseExpression(e); got != test.want {
+				t.Errorf("expression = %s, want %s", got, test.want)
+			}
+			if ids := e.IDs(); !slices.Equal(ids, test.ids) {
+				t.Errorf("ids = %v, want %v", ids, test.ids)
+			}
+		})
+	}
+}
+
+func TestLicenseUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want License
+	}{
+		{name: "expression", json: `"MIT OR ISC"`, want: "MIT OR ISC"},
+		{name: "object", json: `{"type": "MIT", "url": "https://opensource.org/licenses/MIT"}`, want: "MIT"},
+		{name: "list", json: `[{"type": "MIT"}, {"type": "Apache-2.0"}]`, want: "(MIT OR Apache-2.0)"},
+		{name: "list of one", json: `[{"type": "ISC"}]`, want: "ISC"},
+		{name: "unknown format", json: `42`, want: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var pkg PackageJSON
+			if err := json.Unmarshal([]byte(`{"license": `+test.json+`}`), &pkg); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if pkg.License != test.want {
+				t.Errorf("license = %q, want %q", pkg.License, test.want)
+			}
+		})
+	}
+}
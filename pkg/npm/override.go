@@ -0,0 +1,134 @@
+package npm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	overridesKey = "overrides"
+	// overridePathSeparator separates the parents of an overridden package,
+	// e.g. "parent>child@1.0.0" only overrides "child" below "parent"
+	overridePathSeparator = ">"
+	// overrideSelfKey is used for the version of a package that also has
+	// nested overrides
+	overrideSelfKey = "."
+)
+
+// Override forces a package in the tree to the given spec, like an entry in
+// the "overrides" field of package.json.
+// https://docs.npmjs.com/cli/v10/configuring-npm/package-json#overrides
+type Override struct {
+	// Parents are the packages the override is nested in, outermost first
+	Parents []string
+	Spec    Spec
+}
+
+// ParseOverride parses overrides in the form "name@spec", or
+// "parent>name@spec" for overrides nested below other packages.
+func ParseOverride(s string) (Override, error) {
+	parts := splitOverridePath(s)
+
+	for _, parent := range parts[:len(parts)-1] {
+		if err := validatePackageName(parent); err != nil {
+			return Override{}, err
+		}
+	}
+
+	spec, err := ParseSpec(parts[len(parts)-1])
+	if err != nil {
+		return Override{}, err
+	}
+
+	if spec.RawSpec == "" {
+		return Override{}, fmt.Errorf("%w: override for \"%s\" is missing a spec", ErrInvalidSpec, spec.Name)
+	}
+
+	return Override{
+		Parents: parts[:len(parts)-1],
+		Spec:    spec,
+	}, nil
+}
+
+// splitOverridePath splits the parents off an override. Ranges like ">=1.0.0"
+// contain the separator as well, so it only separates parents when it follows
+// a plain name and a spec comes after it.
+func splitOverridePath(s string) []string {
+	var parts []string
+	for {
+		s = strings.TrimSpace(s)
+
+		parent, rest, ok := strings.Cut(s, overridePathSeparator)
+		parent, rest = strings.TrimSpace(parent), strings.TrimSpace(rest)
+		if !ok || specNameEnd(parent) != -1 || specNameEnd(rest) == -1 {
+			return append(parts, s)
+		}
+
+		parts = append(parts, parent)
+		s = rest
+	}
+}
+
+func (o Override) String() string {
+	return strings.Join(append(append([]string{}, o.Parents...), o.Spec.String()), overridePathSeparator)
+}
+
+// AddOverride adds the override to the "overrides" field, keeping existing
+// overrides.
+func (p *PackageJSON) AddOverride(o Override) error {
+	spec, err := json.Marshal(o.Spec.RawSpec)
+	if err != nil {
+		return err
+	}
+
+	p.ensureRaw()
+	p.editRaw(func(raw []byte) ([]byte, error) {
+		path := append(append([]string{overridesKey}, o.Parents...), o.Spec.Name)
+
+		return setNestedOverride(raw, 0, path, spec)
+	})
+
+	return nil
+}
+
+// setNestedOverride sets the value at path below the object starting at
+// start, creating missing objects on the way. String values on the way are
+// turned into objects with the string as their "." member.
+func setNestedOverride(data []byte, start int, path []string, value []byte) ([]byte, error) {
+	if len(path) == 1 {
+		return setJSONMember(data, start, path[0], value)
+	}
+
+	o, err := parseJSONObject(data, start)
+	if err != nil {
+		return nil, err
+	}
+
+	idx, ok := o.member(path[0])
+	if !ok {
+		nested, err := setNestedOverride([]byte("{}"), 0, path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+
+		return setJSONMember(data, start, path[0], nested)
+	}
+
+	m := o.Members[idx]
+	if data[m.ValueStart] == '{' {
+		return setNestedOverride(data, m.ValueStart, path[1:], value)
+	}
+
+	nested, err := setJSONMember([]byte("{}"), 0, overrideSelfKey, data[m.ValueStart:m.ValueEnd])
+	if err != nil {
+		return nil, err
+	}
+
+	nested, err = setNestedOverride(nested, 0, path[1:], value)
+	if err != nil {
+		return nil, err
+	}
+
+	return splice(data, m.ValueStart, m.ValueEnd, nested), nil
+}
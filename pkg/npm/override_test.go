@@ -0,0 +1,51 @@
+package npm
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseOverride(t *testing.T) {
+	tests := []struct {
+		input     string
+		parents   []string
+		name      string
+		rawSpec   string
+		specType  SpecType
+		wantError bool
+	}{
+		{input: "lodash@4.17.21", name: "lodash", rawSpec: "4.17.21", specType: SpecTypeVersion},
+		{input: "lodash@>=4.17.21", name: "lodash", rawSpec: ">=4.17.21", specType: SpecTypeRange},
+		{input: "lodash@>4", name: "lodash", rawSpec: ">4", specType: SpecTypeRange},
+		{input: "lodash >=4.17.21", name: "lodash", rawSpec: ">=4.17.21", specType: SpecTypeRange},
+		{input: "parent>lodash@>=4.17.21", parents: []string{"parent"}, name: "lodash", rawSpec: ">=4.17.21", specType: SpecTypeRange},
+		{input: "a > b > c@>1 <3", parents: []string{"a", "b"}, name: "c", rawSpec: ">1 <3", specType: SpecTypeRange},
+		{input: "@scope/parent>@scope/child@^2.0.0", parents: []string{"@scope/parent"}, name: "@scope/child", rawSpec: "^2.0.0", specType: SpecTypeRange},
+		{input: "parent>child@npm:other@1.0.0", parents: []string{"parent"}, name: "child", rawSpec: "npm:other@1.0.0", specType: SpecTypeAlias},
+		{input: "lodash", wantError: true},
+		{input: "parent>lodash", wantError: true},
+		{input: "_parent>lodash@1.0.0", wantError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			o, err := ParseOverride(test.input)
+			if test.wantError {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", o)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !slices.Equal(o.Parents, test.parents) {
+				t.Errorf("parents = %q, want %q", o.Parents, test.parents)
+			}
+			if o.Spec.Name != test.name || o.Spec.RawSpec != test.rawSpec || o.Spec.Type != test.specType {
+				t.Errorf("spec = %s %q (%s), want %s %q (%s)", o.Spec.Name, o.Spec.RawSpec, o.Spec.Type, test.name, test.rawSpec, test.specType)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package npm
 
 import (
 	"encoding/json"
+	"net/http"
 	"net/url"
 	"slices"
 	"sort"
@@ -12,12 +13,48 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+// abbreviatedAccept requests the abbreviated packument npm installs with,
+// which only has the fields needed to resolve and install versions
+const abbreviatedAccept = "application/vnd.npm.install-v1+json; q=1.0, application/json; q=0.8, */*"
+
+// abbreviatedCachePrefix keeps abbreviated packuments apart from full ones in
+// the cache
+const abbreviatedCachePrefix = "abbreviated:"
+
 func (c *Client) GetPackageInfo(packageName string) (*PackageInfo, error) {
+	return c.getPackageInfo(packageName, false)
+}
+
+// getAbbreviatedPackageInfo fetches the abbreviated packument, which lacks
+// scripts, licenses and release times, so it's only used to resolve trees. A
+// cached full packument is used if there is one.
+func (c *Client) getAbbreviatedPackageInfo(packageName string) (*PackageInfo, error) {
 	if cached, ok := c.cache.Load(packageName); ok {
 		return &cached, nil
 	}
 
-	resp, err := c.c.Get(c.registryBase + "/" + url.PathEscape(packageName))
+	return c.getPackageInfo(packageName, true)
+}
+
+func (c *Client) getPackageInfo(packageName string, abbreviated bool) (*PackageInfo, error) {
+	key := packageName
+	if abbreviated {
+		key = abbreviatedCachePrefix + packageName
+	}
+
+	if cached, ok := c.cache.Load(key); ok {
+		return &cached, nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, c.registryBase+"/"+url.PathEscape(packageName), nil)
+	if err != nil {
+		return nil, err
+	}
+	if abbreviated {
+		req.Header.Set("Accept", abbreviatedAccept)
+	}
+
+	resp, err := c.c.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -35,7 +72,7 @@ func (c *Client) GetPackageInfo(packageName string) (*PackageInfo, error) {
 
 	info.LatestVersion = info.Versions[info.DistTags["latest"]]
 
-	c.cache.Store(packageName, info)
+	c.cache.Store(key, info)
 
 	return &info, nil
 }
@@ -1,23 +1,168 @@
 package npm
 
 import (
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
-	"errors"
 	"fmt"
-	"unicode"
+	"maps"
+	"os"
+	"strings"
 
 	npm_version "github.com/aquasecurity/go-npm-version/pkg"
 	"github.com/rs/zerolog/log"
 )
 
-var (
-	ErrImproperVersionConstraintFormat = errors.New("improper version constraint format")
-)
-
 type PackageJSON struct {
-	Name         string              `json:"name"`
-	Version      string              `json:"version"`
-	Dependencies PackageDependencies `json:"dependencies"`
+	Name                 string                        `json:"name"`
+	Version              string                        `json:"version"`
+	Dependencies         PackageDependencies           `json:"dependencies"`
+	DevDependencies      PackageDependencies           `json:"devDependencies,omitempty"`
+	PeerDependencies     PackageDependencies           `json:"peerDependencies,omitempty"`
+	OptionalDependencies PackageDependencies           `json:"optionalDependencies,omitempty"`
+	PeerDependenciesMeta map[string]PeerDependencyMeta `json:"peerDependenciesMeta,omitempty"`
+
+	// Platforms the package can be installed on, entries starting with "!"
+	// exclude a platform
+	OS   PlatformList `json:"os,omitempty"`
+	CPU  PlatformList `json:"cpu,omitempty"`
+	Libc PlatformList `json:"libc,omitempty"`
+
+	Scripts map[string]string `json:"scripts,omitempty"`
+	// Deprecated is only set for deprecated versions in packuments
+	Deprecated Deprecation `json:"deprecated,omitempty"`
+
+	// Dist is only set for packages from packuments
+	Dist *PackageDist `json:"dist,omitempty"`
+
+	// Flags of lockfile entries, describing why the package is in the tree
+	Dev         bool `json:"dev,omitempty"`
+	Optional    bool `json:"optional,omitempty"`
+	DevOptional bool `json:"devOptional,omitempty"`
+	Peer        bool `json:"peer,omitempty"`
+
+	// Fields of lockfile entries, describing where the package came from
+	Resolved  string  `json:"resolved,omitempty"`
+	Integrity string  `json:"integrity,omitempty"`
+	License   License `json:"license,omitempty"`
+	// Link is set for symlinks to the package at Resolved, e.g. workspaces
+	Link bool `json:"link,omitempty"`
+	// HasInstallScript is set if npm runs a lifecycle script when installing
+	// the package, version 1 lockfiles don't record it
+	HasInstallScript bool `json:"hasInstallScript,omitempty"`
+	// Location is the path of lockfile entries relative to the project, e.g.
+	// "node_modules/a/node_modules/b"
+	Location string `json:"-"`
+
+	// raw is the original document, which is kept up to date with the
+	// changes made through RemoveDependency, AddDependency, AddOverride and
+	// RemoveLifecycleScripts
+	raw []byte
+}
+
+// ParseEditablePackageJSON parses a package.json that gets modified and
+// written back. Unlike other packages, it keeps the original document, so that
+// the fields PackageJSON doesn't model are written back unchanged.
+func ParseEditablePackageJSON(path string) (PackageJSON, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return PackageJSON{}, err
+	}
+
+	var p PackageJSON
+	if err := json.Unmarshal(data, &p); err != nil {
+		return PackageJSON{}, err
+	}
+	p.raw = data
+
+	return p, nil
+}
+
+// JSON returns the document the package was parsed from, including every
+// field that isn't modeled by PackageJSON, with the changes applied.
+func (p PackageJSON) JSON() ([]byte, error) {
+	if p.raw != nil {
+		return p.raw, nil
+	}
+
+	return json.MarshalIndent(p, "", "  ")
+}
+
+// Clone returns a copy of the package whose dependencies can be modified
+// without affecting the original.
+func (p PackageJSON) Clone() PackageJSON {
+	p.Dependencies = maps.Clone(p.Dependencies)
+	p.DevDependencies = maps.Clone(p.DevDependencies)
+	p.PeerDependencies = maps.Clone(p.PeerDependencies)
+	p.OptionalDependencies = maps.Clone(p.OptionalDependencies)
+	p.Scripts = maps.Clone(p.Scripts)
+
+	return p
+}
+
+// ensureRaw creates the document from the modeled fields for packages that
+// weren't parsed from JSON, so that fields without a model can be added.
+func (p *PackageJSON) ensureRaw() {
+	if p.raw != nil {
+		return
+	}
+
+	raw, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		log.Warn().Err(err).Str("package", p.Name).Msg("Failed to encode package.json")
+		return
+	}
+
+	p.raw = raw
+}
+
+// editRaw applies an edit to the original document. If that fails, the
+// original document is dropped and JSON falls back to the modeled fields.
+func (p *PackageJSON) editRaw(edit func(raw []byte) ([]byte, error)) {
+	if p.raw == nil {
+		return
+	}
+
+	raw, err := edit(p.raw)
+	if err != nil {
+		log.Warn().Err(err).Str("package", p.Name).Msg("Failed to edit package.json, unknown fields will be dropped")
+		p.raw = nil
+		return
+	}
+
+	p.raw = raw
+}
+
+// AddDependency adds the dependency to the production dependencies.
+func (p *PackageJSON) AddDependency(toAdd DependencyInfo) error {
+	if err := p.Dependencies.Add(toAdd); err != nil {
+		return err
+	}
+
+	p.editRaw(func(raw []byte) ([]byte, error) {
+		spec, err := json.Marshal(toAdd.RawSpec())
+		if err != nil {
+			return nil, err
+		}
+
+		start, ok, err := memberValueStart(raw, 0, dependencySectionKeys[DependencyKindProd])
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			section, err := setJSONMember([]byte("{}"), 0, toAdd.Name, spec)
+			if err != nil {
+				return nil, err
+			}
+
+			return setJSONMember(raw, 0, dependencySectionKeys[DependencyKindProd], section)
+		}
+
+		return setJSONMember(raw, start, toAdd.Name, spec)
+	})
+
+	return nil
 }
 
 func (p PackageJSON) String() string {
@@ -31,6 +176,208 @@ func (p PackageJSON) AsDependency() DependencyInfo {
 	}
 }
 
+// Kind returns the kind of a lockfile entry. Packages that are both peer and
+// optional dependencies are counted as peer dependencies.
+func (p PackageJSON) Kind() DependencyKind {
+	switch {
+	case p.Peer:
+		return DependencyKindPeer
+	case p.Optional, p.DevOptional:
+		return DependencyKindOptional
+	case p.Dev:
+		return DependencyKindDev
+	}
+
+	return DependencyKindProd
+}
+
+// installScripts are the lifecycle scripts npm runs when installing a package
+var installScripts = []string{"preinstall", "install", "postinstall"}
+
+// rootLifecycleScripts are the scripts npm runs when installing the
+// dependencies of a project
+var rootLifecycleScripts = []string{"preinstall", "install", "postinstall", "prepublish", "preprepare", "prepare", "postprepare"}
+
+// HasInstallScripts reports whether npm runs lifecycle scripts when installing
+// the package, either as recorded in the lockfile or from its scripts.
+func (p PackageJSON) HasInstallScripts() bool {
+	if p.HasInstallScript {
+		return true
+	}
+
+	for _, script := range installScripts {
+		if _, ok := p.Scripts[script]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RemoveLifecycleScripts removes the scripts npm would run when installing the
+// package as a project. They need the files of the package, which aren't there
+// when only its package.json is installed.
+func (p *PackageJSON) RemoveLifecycleScripts() {
+	for _, script := range rootLifecycleScripts {
+		delete(p.Scripts, script)
+	}
+
+	p.editRaw(func(raw []byte) ([]byte, error) {
+		start, ok, err := memberValueStart(raw, 0, "scripts")
+		if err != nil || !ok {
+			return raw, err
+		}
+
+		for _, script := range rootLifecycleScripts {
+			raw, _, err = removeJSONMember(raw, start, script)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return raw, nil
+	})
+}
+
+// DependenciesOfKind returns the dependency section of the given kind.
+func (p PackageJSON) DependenciesOfKind(kind DependencyKind) PackageDependencies {
+	switch kind {
+	case DependencyKindDev:
+		return p.DevDependencies
+	case DependencyKindOptional:
+		return p.OptionalDependencies
+	case DependencyKindPeer:
+		return p.PeerDependencies
+	}
+
+	return p.Dependencies
+}
+
+// RemoveDependency removes the dependency from every section that gets
+// installed by consumers of the package.
+func (p *PackageJSON) RemoveDependency(toRemove DependencyInfo) bool {
+	removed := p.Dependencies.Remove(toRemove)
+	removed = p.OptionalDependencies.Remove(toRemove) || removed
+	removed = p.PeerDependencies.Remove(toRemove) || removed
+
+	for _, kind := range []DependencyKind{DependencyKindProd, DependencyKindOptional, DependencyKindPeer} {
+		p.editRaw(func(raw []byte) ([]byte, error) {
+			start, ok, err := memberValueStart(raw, 0, dependencySectionKeys[kind])
+			if err != nil || !ok {
+				return raw, err
+			}
+
+			raw, _, err = removeJSONMember(raw, start, toRemove.Name)
+			return raw, err
+		})
+	}
+
+	return removed
+}
+
+type PeerDependencyMeta struct {
+	Optional bool `json:"optional"`
+}
+
+type PackageDist struct {
+	Tarball   string `json:"tarball"`
+	Integrity string `json:"integrity,omitempty"`
+	Shasum    string `json:"shasum,omitempty"`
+	// UnpackedSize and FileCount are missing for old versions
+	UnpackedSize *uint64 `json:"unpackedSize,omitempty"`
+	FileCount    *uint64 `json:"fileCount,omitempty"`
+}
+
+// SRI returns the subresource integrity string of the tarball. Old versions
+// only have a SHA-1 checksum.
+func (d PackageDist) SRI() string {
+	if d.Integrity != "" || d.Shasum == "" {
+		return d.Integrity
+	}
+
+	sum, err := hex.DecodeString(d.Shasum)
+	if err != nil {
+		return ""
+	}
+
+	return "sha1-" + base64.StdEncoding.EncodeToString(sum)
+}
+
+// License is an SPDX license expression. Old packages use an object or a
+// list of objects, which are converted to an expression.
+type License string
+
+func (l *License) UnmarshalJSON(data []byte) error {
+	var expression string
+	if err := json.Unmarshal(data, &expression); err == nil {
+		*l = License(expression)
+		return nil
+	}
+
+	type legacyLicense struct {
+		Type string `json:"type"`
+	}
+
+	var single legacyLicense
+	if err := json.Unmarshal(data, &single); err == nil {
+		*l = License(single.Type)
+		return nil
+	}
+
+	var multiple []legacyLicense
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		// An unparsable license shouldn't make the whole package unusable
+		log.Debug().RawJSON("license", data).Msg("Ignoring unknown license format")
+		*l = ""
+		return nil
+	}
+
+	types := make([]string, 0, len(multiple))
+	for _, m := range multiple {
+		types = append(types, m.Type)
+	}
+	if len(types) > 1 {
+		*l = License("(" + strings.Join(types, " OR ") + ")")
+	} else {
+		*l = License(strings.Join(types, ""))
+	}
+
+	return nil
+}
+
+type DependencyKind uint8
+
+const (
+	DependencyKindProd DependencyKind = iota
+	DependencyKindDev
+	DependencyKindOptional
+	DependencyKindPeer
+)
+
+var DependencyKinds = []DependencyKind{DependencyKindProd, DependencyKindDev, DependencyKindOptional, DependencyKindPeer}
+
+var dependencySectionKeys = map[DependencyKind]string{
+	DependencyKindProd:     "dependencies",
+	DependencyKindDev:      "devDependencies",
+	DependencyKindOptional: "optionalDependencies",
+	DependencyKindPeer:     "peerDependencies",
+}
+
+func (k DependencyKind) String() string {
+	switch k {
+	case DependencyKindProd:
+		return "prod"
+	case DependencyKindDev:
+		return "dev"
+	case DependencyKindOptional:
+		return "optional"
+	case DependencyKindPeer:
+		return "peer"
+	}
+
+	return "unknown"
+}
+
 type PackageDependencies map[string]Dependency
 
 func (d *PackageDependencies) UnmarshalJSON(data []byte) error {
@@ -46,13 +393,10 @@ func (d *PackageDependencies) UnmarshalJSON(data []byte) error {
 
 		dep, err := newDependency(name, rawConstraint)
 		if err != nil {
-			if errors.Is(err, ErrImproperVersionConstraintFormat) {
-				l.Warn().Msg("Skipping dependency with improper version constraint format")
-			} else {
-				l.Warn().Err(err).Msg("Failed to create constraints")
-			}
+			// Keep the dependency anyway, so it is written back unchanged
+			l.Warn().Err(err).Msg("Failed to parse dependency spec")
 
-			continue
+			dep = invalidDependency(name, rawConstraint)
 		}
 
 		l.Trace().Msg("Adding dependency")
@@ -83,11 +427,15 @@ func (d *PackageDependencies) Remove(toRemove DependencyInfo) bool {
 }
 
 func (d *PackageDependencies) Add(toAdd DependencyInfo) error {
-	dep, err := newDependency(toAdd.Name, toAdd.Version)
+	dep, err := newDependency(toAdd.Name, toAdd.RawSpec())
 	if err != nil {
 		return err
 	}
 
+	if *d == nil {
+		*d = make(PackageDependencies)
+	}
+
 	(*d)[toAdd.Name] = dep
 
 	return nil
@@ -96,24 +444,63 @@ func (d *PackageDependencies) Add(toAdd DependencyInfo) error {
 type Dependency struct {
 	Name          string
 	RawConstraint string
-	Constraint    npm_version.Constraints
+	Spec          Spec
+	// Constraint is only set for dependencies with a version or range spec
+	Constraint npm_version.Constraints
 }
 
 func newDependency(name, rawConstraint string) (Dependency, error) {
-	if len(rawConstraint) == 0 || unicode.IsLetter(rune(rawConstraint[0])) {
-		return Dependency{}, ErrImproperVersionConstraintFormat
-	}
-
-	c, err := npm_version.NewConstraints(rawConstraint)
+	spec, err := NewSpec(name, rawConstraint)
 	if err != nil {
 		return Dependency{}, err
 	}
 
+	dep := Dependency{
+		Name:          name,
+		RawConstraint: rawConstraint,
+		Spec:          spec,
+	}
+
+	if spec.Type == SpecTypeVersion || spec.Type == SpecTypeRange {
+		dep.Constraint, err = npm_version.NewConstraints(spec.FetchSpec)
+		if err != nil {
+			return Dependency{}, err
+		}
+	}
+
+	return dep, nil
+}
+
+// invalidDependency keeps a dependency whose spec failed to parse, so that it
+// is skipped when looking it up in the registry.
+func invalidDependency(name, rawConstraint string) Dependency {
 	return Dependency{
 		Name:          name,
 		RawConstraint: rawConstraint,
-		Constraint:    c,
-	}, nil
+		Spec: Spec{
+			Raw:       name + "@" + rawConstraint,
+			Name:      name,
+			RawSpec:   rawConstraint,
+			Type:      SpecTypeInvalid,
+			FetchSpec: rawConstraint,
+		},
+	}
+}
+
+// AsDependency returns the dependency info of the dependency as installed,
+// using the version from the lockfile entry.
+func (d Dependency) AsDependency(locked PackageJSON) DependencyInfo {
+	info := DependencyInfo{
+		Name:    d.Name,
+		Version: locked.Version,
+	}
+
+	if d.Spec.Type == SpecTypeAlias || !d.Spec.Type.IsRegistry() {
+		spec := d.Spec
+		info.Spec = &spec
+	}
+
+	return info
 }
 
 func (d Dependency) String() string {
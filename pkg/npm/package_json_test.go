@@ -0,0 +1,82 @@
+package npm
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRemoveLifecycleScripts(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want string
+	}{
+		{
+			name: "postinstall",
+			doc: `{
+  "name": "esbuild",
+  "scripts": {
+    "postinstall": "node install.js",
+    "test": "node test.js"
+  },
+  "custom": true
+}`,
+			want: `{
+  "name": "esbuild",
+  "scripts": {
+    "test": "node test.js"
+  },
+  "custom": true
+}`,
+		},
+		{
+			name: "only lifecycle scripts",
+			doc:  `{"name": "a", "scripts": {"preinstall": "x", "install": "y", "prepare": "z"}}`,
+			want: `{"name": "a", "scripts": {}}`,
+		},
+		{
+			name: "no scripts",
+			doc:  `{"name": "a",  "version": "1.0.0"}`,
+			want: `{"name": "a",  "version": "1.0.0"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p, err := ParseEditablePackageJSON(writeTestFile(t, "package.json", test.doc))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			scripts := len(p.Scripts)
+			modified := p.Clone()
+			modified.RemoveLifecycleScripts()
+
+			got, err := modified.JSON()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != test.want {
+				t.Errorf("document =\n%s\nwant\n%s", got, test.want)
+			}
+
+			if modified.HasInstallScripts() {
+				t.Error("modified package still has install scripts")
+			}
+			if len(p.Scripts) != scripts {
+				t.Error("scripts of the original package were modified")
+			}
+		})
+	}
+}
+
+func TestPackageJSONDecodingDoesntKeepDocument(t *testing.T) {
+	var p PackageJSON
+	if err := json.Unmarshal([]byte(`{"name": "a", "custom": true}`), &p); err != nil {
+		t.Fatal(err)
+	}
+
+	if p.raw != nil {
+		t.Error("decoded package keeps its document")
+	}
+}
@@ -2,40 +2,132 @@ package npm
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"strings"
+
+	"github.com/rs/zerolog/log"
 )
 
+const nodeModulesPrefix = "node_modules/"
+
 type PackageLockJSON struct {
 	LockfileVersion int            `json:"lockfileVersion"`
 	Packages        LockedPackages `json:"packages"`
+	// Root is the project the lockfile belongs to. Version 1 lockfiles don't
+	// record the dependencies of the project, they are read from the
+	// package.json next to the lockfile if there is one.
+	Root PackageJSON `json:"-"`
 }
 
 func ParsePackageLockJSON(path string) (*PackageLockJSON, error) {
-	fd, err := os.OpenFile(path, os.O_RDONLY, 0400)
+	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
-	data, err := io.ReadAll(fd)
-	if err != nil {
-		return nil, err
+	var pl struct {
+		PackageLockJSON
+		// Lockfiles before version 3 describe the tree in the nested
+		// "dependencies" section
+		Dependencies map[string]legacyLockedDependency `json:"dependencies"`
 	}
-
-	var pl PackageLockJSON
 	if err := json.Unmarshal(data, &pl); err != nil {
 		return nil, err
 	}
 
-	if pl.LockfileVersion != 3 {
+	switch pl.LockfileVersion {
+	case 1:
+		pl.Packages = make(LockedPackages)
+		pl.Packages.addLegacyDependencies("", pl.Dependencies)
+
+		var root struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		}
+		if err := json.Unmarshal(data, &root); err != nil {
+			return nil, err
+		}
+		pl.Root = readLegacyRoot(filepath.Join(filepath.Dir(path), "package.json"))
+		if root.Name != "" {
+			pl.Root.Name = root.Name
+			pl.Root.Version = root.Version
+		}
+	case 2, 3:
+		// Version 2 lockfiles contain both sections for backwards
+		// compatibility, the "packages" section is authoritative
+		var root struct {
+			Packages map[string]PackageJSON `json:"packages"`
+		}
+		if err := json.Unmarshal(data, &root); err != nil {
+			return nil, err
+		}
+		pl.Root = root.Packages[""]
+	default:
 		return nil, fmt.Errorf("unsupported lockfile version: %d", pl.LockfileVersion)
 	}
 
-	return &pl, nil
+	return &pl.PackageLockJSON, nil
+}
+
+// readLegacyRoot reads the project's package.json for the dependencies of the
+// root of a version 1 lockfile. The root has no dependencies if it's missing or
+// invalid.
+func readLegacyRoot(path string) PackageJSON {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return PackageJSON{}
+	}
+	if err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("Failed to read the package.json of the lockfile")
+		return PackageJSON{}
+	}
+
+	var root PackageJSON
+	if err := json.Unmarshal(data, &root); err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("Failed to parse the package.json of the lockfile")
+		return PackageJSON{}
+	}
+
+	return root
 }
 
+// MarshalJSON encodes the lockfile in the version 3 format, which npm and
+// ParsePackageLockJSON can read.
+func (l PackageLockJSON) MarshalJSON() ([]byte, error) {
+	packages := make(map[string]PackageJSON, len(l.Packages)+1)
+	packages[""] = l.Root
+
+	for name, pkg := range l.Packages {
+		location := pkg.Location
+		if location == "" {
+			location = nodeModulesPrefix + name
+		}
+
+		packages[location] = pkg
+	}
+
+	return json.Marshal(struct {
+		Name            string                 `json:"name"`
+		Version         string                 `json:"version,omitempty"`
+		LockfileVersion int                    `json:"lockfileVersion"`
+		Requires        bool                   `json:"requires"`
+		Packages        map[string]PackageJSON `json:"packages"`
+	}{
+		Name:            l.Root.Name,
+		Version:         l.Root.Version,
+		LockfileVersion: 3,
+		Requires:        true,
+		Packages:        packages,
+	})
+}
+
+// LockedPackages maps the install path of every package in the tree without
+// the leading "node_modules/", e.g. "a" or "a/node_modules/b" for a nested copy
+// of "b", to its lockfile entry.
 type LockedPackages map[string]PackageJSON
 
 func (p *LockedPackages) UnmarshalJSON(data []byte) error {
@@ -50,7 +142,8 @@ func (p *LockedPackages) UnmarshalJSON(data []byte) error {
 	delete(packages, "")
 
 	for name, pkg := range packages {
-		name := strings.TrimPrefix(name, "node_modules/")
+		pkg.Location = name
+		name := strings.TrimPrefix(name, nodeModulesPrefix)
 
 		pkg.Name = name
 
@@ -59,3 +152,54 @@ func (p *LockedPackages) UnmarshalJSON(data []byte) error {
 
 	return nil
 }
+
+type legacyLockedDependency struct {
+	Version      string                            `json:"version"`
+	Resolved     string                            `json:"resolved"`
+	Integrity    string                            `json:"integrity"`
+	Dev          bool                              `json:"dev"`
+	Optional     bool                              `json:"optional"`
+	Requires     map[string]string                 `json:"requires"`
+	Dependencies map[string]legacyLockedDependency `json:"dependencies"`
+}
+
+// addLegacyDependencies flattens the nested "dependencies" section of version 1
+// lockfiles into the same paths version 3 lockfiles use.
+func (p LockedPackages) addLegacyDependencies(parent string, deps map[string]legacyLockedDependency) {
+	for name, dep := range deps {
+		path := name
+		if parent != "" {
+			path = parent + "/" + nodeModulesPrefix + name
+		}
+
+		version := dep.Version
+		// Aliases are locked as "npm:<name>@<version>"
+		if strings.HasPrefix(version, aliasPrefix) {
+			version = version[strings.LastIndex(version, "@")+1:]
+		}
+
+		pkg := PackageJSON{
+			Name:         path,
+			Version:      version,
+			Dependencies: make(PackageDependencies, len(dep.Requires)),
+			Dev:          dep.Dev,
+			Optional:     dep.Optional,
+			Resolved:     dep.Resolved,
+			Integrity:    dep.Integrity,
+			Location:     nodeModulesPrefix + path,
+		}
+
+		for depName, rawConstraint := range dep.Requires {
+			d, err := newDependency(depName, rawConstraint)
+			if err != nil {
+				d = invalidDependency(depName, rawConstraint)
+			}
+
+			pkg.Dependencies[depName] = d
+		}
+
+		p[path] = pkg
+
+		p.addLegacyDependencies(path, dep.Dependencies)
+	}
+}
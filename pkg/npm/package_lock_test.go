@@ -0,0 +1,150 @@
+package npm
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// lockedTestPackage is the part of a lockfile entry the tests check
+type lockedTestPackage struct {
+	Version  string
+	Location string
+	Dev      bool
+	Requires map[string]SpecType
+}
+
+func TestParsePackageLockJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		lockfile string
+		root     string
+		packages map[string]lockedTestPackage
+	}{
+		{
+			name: "nested v1",
+			lockfile: `{
+				"name": "project",
+				"version": "1.0.0",
+				"lockfileVersion": 1,
+				"dependencies": {
+					"a": {
+						"version": "1.0.0",
+						"resolved": "https://registry.npmjs.org/a/-/a-1.0.0.tgz",
+						"requires": {"b": "^2.0.0", "c": "github:user/c"},
+						"dependencies": {
+							"b": {
+								"version": "2.1.0",
+								"requires": {"d": "not a valid spec"},
+								"dependencies": {
+									"d": {"version": "3.0.0"}
+								}
+							}
+						}
+					},
+					"b": {"version": "1.0.0", "dev": true},
+					"my-c": {"version": "npm:@scope/c@4.0.0"}
+				}
+			}`,
+			root: "project",
+			packages: map[string]lockedTestPackage{
+				"a":                               {Version: "1.0.0", Location: "node_modules/a", Requires: map[string]SpecType{"b": SpecTypeRange, "c": SpecTypeGit}},
+				"a/node_modules/b":                {Version: "2.1.0", Location: "node_modules/a/node_modules/b", Requires: map[string]SpecType{"d": SpecTypeInvalid}},
+				"a/node_modules/b/node_modules/d": {Version: "3.0.0", Location: "node_modules/a/node_modules/b/node_modules/d"},
+				"b":                               {Version: "1.0.0", Location: "node_modules/b", Dev: true},
+				"my-c":                            {Version: "4.0.0", Location: "node_modules/my-c"},
+			},
+		},
+		{
+			name: "v2 ignores the legacy section",
+			lockfile: `{
+				"name": "project",
+				"lockfileVersion": 2,
+				"packages": {
+					"": {"name": "project", "dependencies": {"a": "^1.0.0"}},
+					"node_modules/a": {"version": "1.0.0", "dependencies": {"b": "2"}},
+					"node_modules/a/node_modules/b": {"version": "2.0.0", "dev": true}
+				},
+				"dependencies": {
+					"a": {"version": "0.1.0"},
+					"legacy-only": {"version": "1.0.0"}
+				}
+			}`,
+			root: "project",
+			packages: map[string]lockedTestPackage{
+				"a":                {Version: "1.0.0", Location: "node_modules/a", Requires: map[string]SpecType{"b": SpecTypeRange}},
+				"a/node_modules/b": {Version: "2.0.0", Location: "node_modules/a/node_modules/b", Dev: true},
+			},
+		},
+		{
+			name: "v3",
+			lockfile: `{
+				"lockfileVersion": 3,
+				"packages": {
+					"": {"name": "project"},
+					"node_modules/@scope/a": {"version": "1.0.0"}
+				}
+			}`,
+			root: "project",
+			packages: map[string]lockedTestPackage{
+				"@scope/a": {Version: "1.0.0", Location: "node_modules/@scope/a"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lock, err := ParsePackageLockJSON(writeTestFile(t, "package-lock.json", test.lockfile))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if lock.Root.Name != test.root {
+				t.Errorf("root = %s, want %s", lock.Root.Name, test.root)
+			}
+
+			if len(lock.Packages) != len(test.packages) {
+				t.Errorf("got %d packages, want %d", len(lock.Packages), len(test.packages))
+			}
+
+			for path, want := range test.packages {
+				pkg, ok := lock.Packages[path]
+				if !ok {
+					t.Errorf("package %s is missing", path)
+					continue
+				}
+
+				if pkg.Version != want.Version || pkg.Location != want.Location || pkg.Dev != want.Dev {
+					t.Errorf("package %s = %s at %s (dev %v), want %s at %s (dev %v)", path, pkg.Version, pkg.Location, pkg.Dev, want.Version, want.Location, want.Dev)
+				}
+
+				if len(pkg.Dependencies) != len(want.Requires) {
+					t.Errorf("package %s has %d dependencies, want %d", path, len(pkg.Dependencies), len(want.Requires))
+				}
+				for name, specType := range want.Requires {
+					if d := pkg.Dependencies[name]; d.Spec.Type != specType {
+						t.Errorf("dependency %s of %s is a %s spec, want %s", name, path, d.Spec.Type, specType)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestParsePackageLockJSONUnsupportedVersion(t *testing.T) {
+	_, err := ParsePackageLockJSON(writeTestFile(t, "package-lock.json", `{"lockfileVersion": 4, "packages": {}}`))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
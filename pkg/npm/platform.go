@@ -0,0 +1,140 @@
+package npm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+var ErrInvalidPlatform = errors.New("invalid platform")
+
+// Platform is the platform packages are installed on, using the names of
+// Node.js' process.platform and process.arch. Libc is "glibc" or "musl" and
+// only used on Linux.
+type Platform struct {
+	OS   string
+	CPU  string
+	Libc string
+}
+
+// DefaultPlatform is the platform of the Debian based container packages are
+// measured in.
+var DefaultPlatform = Platform{OS: "linux", CPU: nodeArch(runtime.GOARCH), Libc: "glibc"}
+
+// ParsePlatform parses platforms in the format "os/cpu" or "os/cpu/libc".
+func ParsePlatform(s string) (Platform, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) < 2 || len(parts) > 3 || slices.Contains(parts, "") {
+		return Platform{}, fmt.Errorf("%w: \"%s\" isn't in the format os/cpu[/libc]", ErrInvalidPlatform, s)
+	}
+
+	p := Platform{OS: parts[0], CPU: parts[1]}
+	if len(parts) == 3 {
+		if p.OS != "linux" {
+			return Platform{}, fmt.Errorf("%w: libc can only be set for linux", ErrInvalidPlatform)
+		}
+
+		p.Libc = parts[2]
+	}
+
+	return p, nil
+}
+
+func (p Platform) String() string {
+	if p.Libc == "" {
+		return p.OS + "/" + p.CPU
+	}
+
+	return p.OS + "/" + p.CPU + "/" + p.Libc
+}
+
+// NPMArgs returns the arguments that make npm install for the platform.
+func (p Platform) NPMArgs() []string {
+	args := []string{"--os", p.OS, "--cpu", p.CPU}
+	if p.Libc != "" {
+		args = append(args, "--libc", p.Libc)
+	}
+
+	return args
+}
+
+// Supports reports whether the package can be installed on the platform.
+// Optional dependencies that can't be installed are skipped by npm.
+func (p Platform) Supports(pkg PackageJSON) bool {
+	if !checkPlatformList(p.OS, pkg.OS) || !checkPlatformList(p.CPU, pkg.CPU) {
+		return false
+	}
+
+	// Like npm, packages requiring a libc are only installed on Linux
+	if len(pkg.Libc) > 0 && (p.OS != "linux" || p.Libc == "") {
+		return false
+	}
+
+	return checkPlatformList(p.Libc, pkg.Libc)
+}
+
+// checkPlatformList checks a value against an "os" or "cpu" list the way
+// npm-install-checks does.
+func checkPlatformList(value string, list PlatformList) bool {
+	if len(list) == 0 || (len(list) == 1 && list[0] == "any") {
+		return true
+	}
+
+	negated := 0
+	match := false
+	for _, entry := range list {
+		if len(entry) > 0 && entry[0] == '!' {
+			negated++
+			if entry[1:] == value {
+				return false
+			}
+
+			continue
+		}
+
+		match = match || entry == value
+	}
+
+	return match || negated == len(list)
+}
+
+func nodeArch(goarch string) string {
+	switch goarch {
+	case "amd64":
+		return "x64"
+	case "386":
+		return "ia32"
+	}
+
+	return goarch
+}
+
+// PlatformList is the value of the "os", "cpu" or "libc" fields, which old packages
+// set to a single string.
+type PlatformList []string
+
+func (l *PlatformList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*l = PlatformList{single}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		// Like with licenses, a broken field shouldn't make the whole package
+		// unusable
+		log.Debug().RawJSON("platforms", data).Msg("Ignoring unknown platform list format")
+		*l = nil
+		return nil
+	}
+
+	*l = list
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package npm
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestPlatformSupports(t *testing.T) {
+	linux := Platform{OS: "linux", CPU: "x64", Libc: "glibc"}
+	musl := Platform{OS: "linux", CPU: "arm64", Libc: "musl"}
+	darwin := Platform{OS: "darwin", CPU: "arm64"}
+	win32 := Platform{OS: "win32", CPU: "x64"}
+
+	tests := []struct {
+		name     string
+		platform Platform
+		// pkg are the platform fields of the package.json
+		pkg  string
+		want bool
+	}{
+		{name: "no restrictions", platform: linux, pkg: `{}`, want: true},
+		{name: "empty lists", platform: win32, pkg: `{"os": [], "cpu": [], "libc": []}`, want: true},
+		{name: "any", platform: darwin, pkg: `{"os": ["any"], "cpu": "any"}`, want: true},
+		{name: "listed os", platform: darwin, pkg: `{"os": ["darwin", "linux"]}`, want: true},
+		{name: "unlisted os", platform: win32, pkg: `{"os": ["darwin", "linux"]}`, want: false},
+		{name: "single string", platform: linux, pkg: `{"os": "darwin"}`, want: false},
+		{name: "negated os", platform: linux, pkg: `{"os": ["!win32"]}`, want: true},
+		{name: "negated own os", platform: win32, pkg: `{"os": ["!win32"]}`, want: false},
+		{name: "negation and listed os", platform: darwin, pkg: `{"os": ["!win32", "linux"]}`, want: false},
+		{name: "unlisted cpu", platform: linux, pkg: `{"os": ["linux"], "cpu": ["arm64"]}`, want: false},
+		{name: "negated cpu", platform: musl, pkg: `{"cpu": ["!x64", "!ia32"]}`, want: true},
+		{name: "matching libc", platform: musl, pkg: `{"os": ["linux"], "libc": ["musl"]}`, want: true},
+		{name: "libc mismatch", platform: linux, pkg: `{"os": ["linux"], "libc": ["musl"]}`, want: false},
+		{name: "negated libc", platform: linux, pkg: `{"libc": ["!musl"]}`, want: true},
+		{name: "libc outside of linux", platform: darwin, pkg: `{"libc": ["glibc"]}`, want: false},
+		{name: "libc without a libc", platform: Platform{OS: "linux", CPU: "x64"}, pkg: `{"libc": ["glibc"]}`, want: false},
+		{name: "broken list", platform: win32, pkg: `{"os": {"darwin": true}}`, want: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var pkg PackageJSON
+			if err := json.Unmarshal([]byte(test.pkg), &pkg); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := test.platform.Supports(pkg); got != test.want {
+				t.Errorf("%s supports %s = %v, want %v", test.platform, test.pkg, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParsePlatform(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Platform
+		wantErr bool
+	}{
+		{input: "darwin/arm64", want: Platform{OS: "darwin", CPU: "arm64"}},
+		{input: "linux/x64/musl", want: Platform{OS: "linux", CPU: "x64", Libc: "musl"}},
+		{input: "linux", wantErr: true},
+		{input: "linux//glibc", wantErr: true},
+		{input: "darwin/arm64/glibc", wantErr: true},
+		{input: "linux/x64/glibc/extra", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			got, err := ParsePlatform(test.input)
+			if test.wantErr {
+				if !errors.Is(err, ErrInvalidPlatform) {
+					t.Errorf("error = %v, want %v", err, ErrInvalidPlatform)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != test.want {
+				t.Errorf("platform = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,294 @@
+package npm
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// maxConcurrentResolves limits the packuments that are fetched at once while
+// resolving a tree
+const maxConcurrentResolves = 16
+
+// ResolvedTree is a dependency tree resolved from packuments, without
+// installing it. Every version is only counted once, while npm installs the
+// same version more than once if it can't be deduplicated, so it is an
+// estimate of the installed tree.
+type ResolvedTree struct {
+	Root     PackageJSON
+	Platform Platform
+	// Packages maps name@version to the resolved packages, including the root
+	Packages map[string]PackageJSON
+	// Dependencies maps name@version of every package to what its
+	// dependencies resolved to, by the name they are installed as
+	Dependencies map[string]map[string]ResolvedDependency
+	// Unresolved are dependencies that can't be resolved from the registry,
+	// e.g. git dependencies or ranges without a matching version
+	Unresolved []UnresolvedDependency
+}
+
+type ResolvedDependency struct {
+	// Package is the name@version of the resolved package
+	Package string
+	Kind    DependencyKind
+}
+
+type UnresolvedDependency struct {
+	// From is the name@version of the package depending on the dependency
+	From string
+	Name string
+	Spec string
+	Err  error
+}
+
+func (d UnresolvedDependency) String() string {
+	return fmt.Sprintf("%s: %s@%s", d.From, d.Name, d.Spec)
+}
+
+// treeDependency is a dependency that is installed together with a package.
+type treeDependency struct {
+	Dependency
+	Kind DependencyKind
+}
+
+// ResolveTree resolves every production, optional and required peer
+// dependency of the package, like npm would on the platform. Optional
+// dependencies for other platforms are skipped. The packages are from
+// abbreviated packuments, so they have no scripts or licenses.
+func (c *Client) ResolveTree(root PackageJSON, platform Platform) *ResolvedTree {
+	t := &ResolvedTree{
+		Root:     root,
+		Platform: platform,
+		Packages: map[string]PackageJSON{root.String(): root},
+
+		Dependencies: map[string]map[string]ResolvedDependency{},
+	}
+
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, maxConcurrentResolves)
+	)
+
+	var visit func(pkg PackageJSON)
+	visit = func(pkg PackageJSON) {
+		for _, dep := range treeDependencies(pkg) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				sem <- struct{}{}
+				resolved, err := c.resolveTreeDependency(dep.Dependency)
+				<-sem
+
+				mu.Lock()
+				if err != nil {
+					log.Debug().Err(err).Str("from", pkg.String()).Str("dependency", dep.String()).Msg("Failed to resolve dependency")
+					t.Unresolved = append(t.Unresolved, UnresolvedDependency{
+						From: pkg.String(),
+						Name: dep.Name,
+						Spec: dep.RawConstraint,
+						Err:  err,
+					})
+					mu.Unlock()
+					return
+				}
+
+				if dep.Kind == DependencyKindOptional && !platform.Supports(resolved) {
+					log.Trace().Str("dependency", resolved.String()).Str("platform", platform.String()).Msg("Skipping optional dependency for other platforms")
+					mu.Unlock()
+					return
+				}
+
+				key := resolved.String()
+
+				from := pkg.String()
+				if t.Dependencies[from] == nil {
+					t.Dependencies[from] = map[string]ResolvedDependency{}
+				}
+				t.Dependencies[from][dep.Name] = ResolvedDependency{Package: key, Kind: dep.Kind}
+
+				if _, ok := t.Packages[key]; ok {
+					mu.Unlock()
+					return
+				}
+				t.Packages[key] = resolved
+				mu.Unlock()
+
+				visit(resolved)
+			}()
+		}
+	}
+	visit(root)
+	wg.Wait()
+
+	slices.SortFunc(t.Unresolved, func(a, b UnresolvedDependency) int {
+		return cmp.Or(cmp.Compare(a.From, b.From), cmp.Compare(a.Name, b.Name))
+	})
+
+	return t
+}
+
+func (c *Client) resolveTreeDependency(dep Dependency) (PackageJSON, error) {
+	if dep.Spec.Name == "" {
+		return PackageJSON{}, fmt.Errorf("%w: %s", ErrInvalidSpec, dep.RawConstraint)
+	}
+
+	if !dep.Spec.Type.IsRegistry() {
+		return PackageJSON{}, fmt.Errorf("%w: %s dependencies can't be resolved from the registry", ErrInvalidSpec, dep.Spec.Type)
+	}
+
+	version, err := c.resolveAbbreviatedSpec(dep.Spec)
+	if err != nil {
+		return PackageJSON{}, err
+	}
+
+	return version.JSON, nil
+}
+
+// treeDependencies returns the dependencies npm installs together with the
+// package, sorted by name. Dependencies that are also listed as optional
+// dependencies are optional.
+func treeDependencies(pkg PackageJSON) []treeDependency {
+	byName := map[string]treeDependency{}
+
+	for _, dep := range pkg.PeerDependencies {
+		if meta, ok := pkg.PeerDependenciesMeta[dep.Name]; ok && meta.Optional {
+			continue
+		}
+
+		byName[dep.Name] = treeDependency{Dependency: dep, Kind: DependencyKindPeer}
+	}
+	for _, dep := range pkg.Dependencies {
+		byName[dep.Name] = treeDependency{Dependency: dep, Kind: DependencyKindProd}
+	}
+	for _, dep := range pkg.OptionalDependencies {
+		byName[dep.Name] = treeDependency{Dependency: dep, Kind: DependencyKindOptional}
+	}
+
+	deps := make([]treeDependency, 0, len(byName))
+	for _, dep := range byName {
+		deps = append(deps, dep)
+	}
+
+	slices.SortFunc(deps, func(a, b treeDependency) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	return deps
+}
+
+// UnpackedSize sums the unpacked sizes and file counts the registry reports
+// for the resolved packages. Unknown is the number of packages the registry
+// doesn't report a size for, which are published by old npm versions.
+func (t *ResolvedTree) UnpackedSize() (size, files uint64, unknown int) {
+	for _, pkg := range t.Packages {
+		if pkg.Dist == nil || pkg.Dist.UnpackedSize == nil {
+			unknown++
+			continue
+		}
+
+		size += *pkg.Dist.UnpackedSize
+		if pkg.Dist.FileCount != nil {
+			files += *pkg.Dist.FileCount
+		}
+	}
+
+	return size, files, unknown
+}
+
+// Lockfile lays out the resolved tree the way npm would install it. Every
+// dependency is placed as close to the root as possible without conflicting
+// with another version on the way, which approximates npm's hoisting.
+func (t *ResolvedTree) Lockfile() *PackageLockJSON {
+	lock := &PackageLockJSON{
+		LockfileVersion: 3,
+		Packages:        LockedPackages{},
+		Root:            t.Root,
+	}
+
+	type queued struct {
+		location string
+		key      string
+	}
+
+	// placed maps locations to the name@version installed there
+	placed := map[string]string{"": t.Root.String()}
+	queue := []queued{{location: "", key: t.Root.String()}}
+
+	for len(queue) > 0 {
+		q := queue[0]
+		queue = queue[1:]
+
+		deps := t.Dependencies[q.key]
+		names := make([]string, 0, len(deps))
+		for name := range deps {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+
+		for _, name := range names {
+			dep := deps[name]
+
+			target := ""
+			deduplicated := false
+			conflict := false
+			for candidate := q.location; ; candidate = parentLocation(candidate) {
+				if key, ok := placed[joinLocation(candidate, name)]; ok {
+					deduplicated = key == dep.Package
+					conflict = !deduplicated && candidate == q.location
+					break
+				}
+
+				target = candidate
+				if candidate == "" {
+					break
+				}
+			}
+
+			if deduplicated {
+				continue
+			}
+			if conflict {
+				log.Warn().Str("location", q.location).Str("dependency", dep.Package).Msg("Dependency conflicts with a hoisted package, skipping it")
+				continue
+			}
+
+			location := joinLocation(target, name)
+			placed[location] = dep.Package
+
+			pkg := t.Packages[dep.Package]
+			pkg.Location = location
+			pkg.Name = strings.TrimPrefix(location, nodeModulesPrefix)
+			pkg.Optional = dep.Kind == DependencyKindOptional
+			pkg.Peer = dep.Kind == DependencyKindPeer
+			pkg.HasInstallScript = pkg.HasInstallScripts()
+			if pkg.Dist != nil {
+				pkg.Resolved = pkg.Dist.Tarball
+				pkg.Integrity = pkg.Dist.SRI()
+			}
+
+			lock.Packages[pkg.Name] = pkg
+			queue = append(queue, queued{location: location, key: dep.Package})
+		}
+	}
+
+	return lock
+}
+
+func joinLocation(parent, name string) string {
+	if parent == "" {
+		return nodeModulesPrefix + name
+	}
+
+	return parent + "/" + nodeModulesPrefix + name
+}
+
+// Subdependencies returns the number of resolved packages without the root.
+func (t *ResolvedTree) Subdependencies() uint64 {
+	return uint64(len(t.Packages) - 1)
+}
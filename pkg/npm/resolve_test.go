@@ -0,0 +1,218 @@
+package npm
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// newRegistryTestServer stubs the registry with the packuments and records the
+// Accept headers it was queried with
+func newRegistryTestServer(t *testing.T, packuments map[string]string) (*httptest.Server, func() []string) {
+	var (
+		mu      sync.Mutex
+		accepts []string
+	)
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		accepts = append(accepts, r.Header.Get("Accept"))
+		mu.Unlock()
+
+		packument, ok := packuments[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		fmt.Fprint(w, packument)
+	}))
+	t.Cleanup(s.Close)
+
+	return s, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return accepts
+	}
+}
+
+// testPackument builds a packument of the versions, which map to the
+// dependencies of the version as JSON, e.g. `{"b": "^1.0.0"}`
+func testPackument(name, latest string, versions map[string]string) string {
+	entries := make([]string, 0, len(versions))
+	for version, deps := range versions {
+		if deps == "" {
+			deps = "{}"
+		}
+
+		entries = append(entries, fmt.Sprintf(
+			`"%s": {"name": "%s", "version": "%s", "dependencies": %s, "dist": {"tarball": "https://registry.npmjs.org/%s/-/%s-%s.tgz", "unpackedSize": 100, "fileCount": 1}}`,
+			version, name, version, deps, name, name, version,
+		))
+	}
+
+	return fmt.Sprintf(`{"name": "%s", "dist-tags": {"latest": "%s"}, "versions": {%s}}`, name, latest, strings.Join(entries, ","))
+}
+
+func TestResolveTree(t *testing.T) {
+	tests := []struct {
+		name       string
+		packuments map[string]string
+		root       string
+		// packages are the name@version of the resolved packages, without the
+		// root
+		packages []string
+		// locations map the locations in the lockfile to the installed
+		// versions
+		locations  map[string]string
+		unresolved []string
+	}{
+		{
+			name: "dedupe",
+			packuments: map[string]string{
+				"a": testPackument("a", "1.0.0", map[string]string{"1.0.0": `{"c": "^1.0.0"}`}),
+				"b": testPackument("b", "1.0.0", map[string]string{"1.0.0": `{"c": "^1.1.0"}`}),
+				"c": testPackument("c", "1.2.0", map[string]string{"1.0.0": "", "1.2.0": ""}),
+			},
+			root:     `{"a": "^1.0.0", "b": "^1.0.0"}`,
+			packages: []string{"a@1.0.0", "b@1.0.0", "c@1.2.0"},
+			locations: map[string]string{
+				"node_modules/a": "1.0.0",
+				"node_modules/b": "1.0.0",
+				"node_modules/c": "1.2.0",
+			},
+		},
+		{
+			name: "nested conflict",
+			packuments: map[string]string{
+				"a": testPackument("a", "1.0.0", map[string]string{"1.0.0": `{"c": "^2.0.0"}`}),
+				"c": testPackument("c", "2.0.0", map[string]string{"1.0.0": "", "2.0.0": ""}),
+			},
+			root:     `{"a": "^1.0.0", "c": "^1.0.0"}`,
+			packages: []string{"a@1.0.0", "c@1.0.0", "c@2.0.0"},
+			locations: map[string]string{
+				"node_modules/a":                "1.0.0",
+				"node_modules/c":                "1.0.0",
+				"node_modules/a/node_modules/c": "2.0.0",
+			},
+		},
+		{
+			name: "latest tag preferred",
+			packuments: map[string]string{
+				"a": testPackument("a", "1.0.0", map[string]string{"1.0.0": "", "1.5.0": "", "2.0.0": ""}),
+				"b": testPackument("b", "1.0.0", map[string]string{"1.0.0": "", "1.5.0": ""}),
+			},
+			root:     `{"a": "^1.0.0", "b": "^1.1.0"}`,
+			packages: []string{"a@1.0.0", "b@1.5.0"},
+			locations: map[string]string{
+				"node_modules/a": "1.0.0",
+				"node_modules/b": "1.5.0",
+			},
+		},
+		{
+			name: "cycle",
+			packuments: map[string]string{
+				"a": testPackument("a", "1.0.0", map[string]string{"1.0.0": `{"b": "^1.0.0"}`}),
+				"b": testPackument("b", "1.0.0", map[string]string{"1.0.0": `{"a": "^1.0.0"}`}),
+			},
+			root:     `{"a": "^1.0.0"}`,
+			packages: []string{"a@1.0.0", "b@1.0.0"},
+			locations: map[string]string{
+				"node_modules/a": "1.0.0",
+				"node_modules/b": "1.0.0",
+			},
+		},
+		{
+			name: "unresolvable",
+			packuments: map[string]string{
+				"a": testPackument("a", "1.0.0", map[string]string{"1.0.0": ""}),
+			},
+			root:       `{"a": "^2.0.0", "b": "github:user/b", "missing": "^1.0.0"}`,
+			packages:   nil,
+			locations:  map[string]string{},
+			unresolved: []string{"root@1.0.0: a@^2.0.0", "root@1.0.0: b@github:user/b", "root@1.0.0: missing@^1.0.0"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, accepts := newRegistryTestServer(t, test.packuments)
+			c := New(WithBaseURLs(s.URL, s.URL))
+
+			var root PackageJSON
+			if err := json.Unmarshal([]byte(fmt.Sprintf(`{"name": "root", "version": "1.0.0", "dependencies": %s}`, test.root)), &root); err != nil {
+				t.Fatal(err)
+			}
+
+			tree := c.ResolveTree(root, DefaultPlatform)
+
+			var packages []string
+			for key := range tree.Packages {
+				if key != root.String() {
+					packages = append(packages, key)
+				}
+			}
+			slices.Sort(packages)
+			if !slices.Equal(packages, test.packages) {
+				t.Errorf("packages = %v, want %v", packages, test.packages)
+			}
+
+			locations := map[string]string{}
+			for _, pkg := range tree.Lockfile().Packages {
+				locations[pkg.Location] = pkg.Version
+			}
+			if !maps.Equal(locations, test.locations) {
+				t.Errorf("locations = %v, want %v", locations, test.locations)
+			}
+
+			var unresolved []string
+			for _, d := range tree.Unresolved {
+				unresolved = append(unresolved, d.String())
+			}
+			if !slices.Equal(unresolved, test.unresolved) {
+				t.Errorf("unresolved = %v, want %v", unresolved, test.unresolved)
+			}
+
+			size, files, unknown := tree.UnpackedSize()
+			if want := uint64(100 * len(test.packages)); size != want || files != uint64(len(test.packages)) || unknown != 1 {
+				t.Errorf("unpacked size = %d, %d files, %d unknown, want %d, %d files and only the root unknown", size, files, unknown, want, len(test.packages))
+			}
+
+			for _, accept := range accepts() {
+				if accept != abbreviatedAccept {
+					t.Errorf("packument requested with Accept %q, want the abbreviated packument", accept)
+				}
+			}
+		})
+	}
+}
+
+func TestGetPackageInfoKeepsFullPackument(t *testing.T) {
+	s, accepts := newRegistryTestServer(t, map[string]string{
+		"a": testPackument("a", "1.0.0", map[string]string{"1.0.0": ""}),
+	})
+	c := New(WithBaseURLs(s.URL, s.URL))
+
+	if _, err := c.getAbbreviatedPackageInfo("a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetPackageInfo("a"); err != nil {
+		t.Fatal(err)
+	}
+	// The full packument is cached now and satisfies the resolver
+	if _, err := c.getAbbreviatedPackageInfo("a"); err != nil {
+		t.Fatal(err)
+	}
+
+	got := accepts()
+	if len(got) != 2 || got[0] != abbreviatedAccept || got[1] != "" {
+		t.Errorf("Accept headers = %q, want the abbreviated and then the full packument", got)
+	}
+}
@@ -0,0 +1,409 @@
+package npm
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	npm_version "github.com/aquasecurity/go-npm-version/pkg"
+)
+
+var (
+	ErrInvalidPackageName = errors.New("invalid package name")
+	ErrInvalidSpec        = errors.New("invalid package spec")
+	ErrUnknownDistTag     = errors.New("unknown dist-tag")
+	ErrNoMatchingVersion  = errors.New("no matching version")
+)
+
+const (
+	DefaultDistTag = "latest"
+
+	aliasPrefix     = "npm:"
+	filePrefix      = "file:"
+	linkPrefix      = "link:"
+	workspacePrefix = "workspace:"
+)
+
+var (
+	gitPrefixes       = []string{"git+ssh://", "git+https://", "git+http://", "git+file://", "git://"}
+	hostedGitPrefixes = []string{"github:", "gitlab:", "bitbucket:", "gist:"}
+	remotePrefixes    = []string{"https://", "http://"}
+	pathPrefixes      = []string{"./", "../", "/", "~/", ".\\", "..\\"}
+	tarballSuffixes   = []string{".tgz", ".tar.gz", ".tar"}
+)
+
+type SpecType uint8
+
+const (
+	SpecTypeTag SpecType = iota
+	SpecTypeVersion
+	SpecTypeRange
+	SpecTypeAlias
+	SpecTypeGit
+	SpecTypeRemote
+	SpecTypeFile
+	SpecTypeDirectory
+	SpecTypeLink
+	SpecTypeWorkspace
+	// SpecTypeInvalid is the type of dependency specs that failed to parse,
+	// they are kept so the document can be written back unchanged
+	SpecTypeInvalid
+)
+
+func (t SpecType) String() string {
+	switch t {
+	case SpecTypeTag:
+		return "tag"
+	case SpecTypeVersion:
+		return "version"
+	case SpecTypeRange:
+		return "range"
+	case SpecTypeAlias:
+		return "alias"
+	case SpecTypeGit:
+		return "git"
+	case SpecTypeRemote:
+		return "tarball"
+	case SpecTypeFile:
+		return "file"
+	case SpecTypeDirectory:
+		return "directory"
+	case SpecTypeLink:
+		return "link"
+	case SpecTypeWorkspace:
+		return "workspace"
+	case SpecTypeInvalid:
+		return "invalid"
+	}
+
+	return "unknown"
+}
+
+// IsRegistry reports whether specs of this type are fetched from the registry.
+func (t SpecType) IsRegistry() bool {
+	return t <= SpecTypeAlias
+}
+
+// IsLocal reports whether specs of this type point to the local filesystem or
+// workspace, which means they can't be installed anywhere else.
+func (t SpecType) IsLocal() bool {
+	return t >= SpecTypeFile && t <= SpecTypeWorkspace
+}
+
+// Spec is a parsed package specifier like "foo", "foo@^1.2.0", "@scope/foo@next"
+// or "foo@npm:bar@1", modeled after npm-package-arg.
+type Spec struct {
+	// Raw is the specifier as it was passed in
+	Raw string
+	// Name is the name the package gets installed as
+	Name string
+	// RawSpec is everything after the name, e.g. "^1.2.0"
+	RawSpec string
+	Type    SpecType
+	// FetchSpec is the normalized tag, version or range used for resolving, or
+	// the URL or path for specs that aren't fetched from the registry
+	FetchSpec string
+	// SubSpec is the aliased package for specs of type SpecTypeAlias
+	SubSpec *Spec
+}
+
+// ParseSpec parses a specifier in the form "name", "name@spec" or "name spec".
+func ParseSpec(arg string) (Spec, error) {
+	arg = strings.TrimSpace(arg)
+
+	nameEnd := specNameEnd(arg)
+	name, rawSpec := arg, ""
+	if nameEnd != -1 {
+		name, rawSpec = arg[:nameEnd], strings.TrimSpace(arg[nameEnd+1:])
+	}
+
+	s, err := NewSpec(name, rawSpec)
+	s.Raw = arg
+	return s, err
+}
+
+// specNameEnd returns the index of the separator between the name and the
+// spec, or -1 if there is no spec. The name ends at the first "@" or space,
+// but the "@" of a scope is not the separator.
+func specNameEnd(arg string) int {
+	for i, r := range arg {
+		if r == ' ' || (r == '@' && i > 0) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// NewSpec parses the spec of a package with a known name, which is what
+// package.json dependencies consist of.
+func NewSpec(name, rawSpec string) (Spec, error) {
+	s := Spec{
+		Raw:     name + "@" + rawSpec,
+		Name:    name,
+		RawSpec: rawSpec,
+	}
+
+	if err := validatePackageName(name); err != nil {
+		return s, err
+	}
+
+	if strings.HasPrefix(rawSpec, aliasPrefix) {
+		sub, err := ParseSpec(strings.TrimPrefix(rawSpec, aliasPrefix))
+		if err != nil {
+			return s, err
+		}
+		if sub.Type == SpecTypeAlias {
+			return s, fmt.Errorf("%w: nested aliases are not supported: %s", ErrInvalidSpec, rawSpec)
+		}
+
+		if !sub.Type.IsRegistry() {
+			return s, fmt.Errorf("%w: aliases must point to registry packages: %s", ErrInvalidSpec, rawSpec)
+		}
+
+		s.Type = SpecTypeAlias
+		s.SubSpec = &sub
+
+		return s, nil
+	}
+
+	if t, fetchSpec, ok := parseNonRegistrySpec(rawSpec); ok {
+		s.Type = t
+		s.FetchSpec = fetchSpec
+
+		return s, nil
+	}
+
+	spec := strings.TrimSpace(rawSpec)
+	if spec == "" {
+		s.Type = SpecTypeTag
+		s.FetchSpec = DefaultDistTag
+
+		return s, nil
+	}
+
+	if v, err := parseLooseVersion(spec); err == nil {
+		s.Type = SpecTypeVersion
+		s.FetchSpec = v.String()
+
+		return s, nil
+	}
+
+	if _, err := npm_version.NewConstraints(spec); err == nil {
+		s.Type = SpecTypeRange
+		s.FetchSpec = spec
+
+		return s, nil
+	}
+
+	if url.PathEscape(spec) != spec {
+		return s, fmt.Errorf("%w: invalid tag name \"%s\"", ErrInvalidSpec, spec)
+	}
+
+	s.Type = SpecTypeTag
+	s.FetchSpec = spec
+
+	return s, nil
+}
+
+// RegistryName returns the name of the package that gets fetched from the
+// registry, which differs from Name for aliases.
+func (s Spec) RegistryName() string {
+	if s.SubSpec != nil {
+		return s.SubSpec.Name
+	}
+
+	return s.Name
+}
+
+// Target returns the spec that has to be resolved against the registry.
+func (s Spec) Target() Spec {
+	if s.SubSpec != nil {
+		return *s.SubSpec
+	}
+
+	return s
+}
+
+func (s Spec) String() string {
+	if s.RawSpec == "" {
+		return s.Name
+	}
+
+	return fmt.Sprintf("%s@%s", s.Name, s.RawSpec)
+}
+
+// Matches reports whether the given version of the package satisfies the
+// spec. Tags match the version they point to.
+func (s Spec) Matches(p *PackageInfo, v npm_version.Version) bool {
+	t := s.Target()
+
+	switch t.Type {
+	case SpecTypeTag:
+		tagged, ok := p.DistTags[t.FetchSpec]
+		return ok && tagged == v.String()
+	case SpecTypeVersion:
+		return t.FetchSpec == v.String()
+	case SpecTypeRange:
+		c, err := npm_version.NewConstraints(t.FetchSpec)
+		return err == nil && c.Check(v)
+	}
+
+	return false
+}
+
+// Resolve picks the version of the package that npm would install for the
+// spec.
+func (p *PackageInfo) Resolve(s Spec) (*PackageVersion, error) {
+	t := s.Target()
+
+	switch t.Type {
+	case SpecTypeTag:
+		version, ok := p.DistTags[t.FetchSpec]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownDistTag, t.FetchSpec)
+		}
+
+		v, ok := p.Versions[version]
+		if !ok {
+			return nil, fmt.Errorf("%w: dist-tag \"%s\" points to missing version %s", ErrNoMatchingVersion, t.FetchSpec, version)
+		}
+
+		return &v, nil
+	case SpecTypeVersion:
+		v, ok := p.Versions[t.FetchSpec]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrNoMatchingVersion, t.FetchSpec)
+		}
+
+		return &v, nil
+	case SpecTypeRange:
+		c, err := npm_version.NewConstraints(t.FetchSpec)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidSpec, err)
+		}
+
+		// npm prefers the version tagged as latest if it satisfies the range
+		if latest, ok := p.Versions[p.DistTags[DefaultDistTag]]; ok && c.Check(latest.Version) {
+			return &latest, nil
+		}
+
+		v := p.Versions.Match(c)
+		if v == nil {
+			return nil, fmt.Errorf("%w: %s", ErrNoMatchingVersion, t.FetchSpec)
+		}
+
+		return v, nil
+	}
+
+	return nil, fmt.Errorf("%w: can't resolve spec of type %s", ErrInvalidSpec, t.Type)
+}
+
+// ResolveSpec fetches the package info of the spec's package and resolves the
+// spec against it.
+func (c *Client) ResolveSpec(s Spec) (*PackageInfo, *PackageVersion, error) {
+	info, err := c.GetPackageInfo(s.RegistryName())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	version, err := info.Resolve(s)
+	if err != nil {
+		return info, nil, err
+	}
+
+	return info, version, nil
+}
+
+// resolveAbbreviatedSpec resolves the spec against the abbreviated packument
+// of its package, see getAbbreviatedPackageInfo.
+func (c *Client) resolveAbbreviatedSpec(s Spec) (*PackageVersion, error) {
+	info, err := c.getAbbreviatedPackageInfo(s.RegistryName())
+	if err != nil {
+		return nil, err
+	}
+
+	return info.Resolve(s)
+}
+
+func parseNonRegistrySpec(rawSpec string) (SpecType, string, bool) {
+	switch {
+	case strings.HasPrefix(rawSpec, workspacePrefix):
+		return SpecTypeWorkspace, strings.TrimPrefix(rawSpec, workspacePrefix), true
+	case strings.HasPrefix(rawSpec, linkPrefix):
+		return SpecTypeLink, strings.TrimPrefix(rawSpec, linkPrefix), true
+	case strings.HasPrefix(rawSpec, filePrefix):
+		return pathSpecType(strings.TrimPrefix(rawSpec, filePrefix)), strings.TrimPrefix(rawSpec, filePrefix), true
+	case hasAnyPrefix(rawSpec, pathPrefixes):
+		return pathSpecType(rawSpec), rawSpec, true
+	case hasAnyPrefix(rawSpec, gitPrefixes), hasAnyPrefix(rawSpec, hostedGitPrefixes):
+		return SpecTypeGit, rawSpec, true
+	case hasAnyPrefix(rawSpec, remotePrefixes):
+		if strings.HasSuffix(strings.SplitN(rawSpec, "#", 2)[0], ".git") {
+			return SpecTypeGit, rawSpec, true
+		}
+
+		return SpecTypeRemote, rawSpec, true
+	}
+
+	// "user/repo" and "user/repo#ref" are shortcuts for GitHub repositories
+	repo := strings.SplitN(rawSpec, "#", 2)[0]
+	if user, project, ok := strings.Cut(repo, "/"); ok && user != "" && project != "" && !strings.ContainsAny(project, "/ ") && !strings.HasPrefix(user, "@") {
+		return SpecTypeGit, rawSpec, true
+	}
+
+	return 0, "", false
+}
+
+func pathSpecType(path string) SpecType {
+	for _, suffix := range tarballSuffixes {
+		if strings.HasSuffix(path, suffix) {
+			return SpecTypeFile
+		}
+	}
+
+	return SpecTypeDirectory
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseLooseVersion(s string) (npm_version.Version, error) {
+	s = strings.TrimPrefix(s, "=")
+	s = strings.TrimPrefix(s, "v")
+
+	return npm_version.NewVersion(s)
+}
+
+func validatePackageName(name string) error {
+	if name == "" || len(name) > 214 {
+		return fmt.Errorf("%w: \"%s\"", ErrInvalidPackageName, name)
+	}
+
+	if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || strings.TrimSpace(name) != name {
+		return fmt.Errorf("%w: \"%s\"", ErrInvalidPackageName, name)
+	}
+
+	if strings.HasPrefix(name, "@") {
+		scope, pkg, ok := strings.Cut(name[1:], "/")
+		if !ok || scope == "" || pkg == "" || strings.Contains(pkg, "/") {
+			return fmt.Errorf("%w: \"%s\"", ErrInvalidPackageName, name)
+		}
+
+		return nil
+	}
+
+	if strings.Contains(name, "/") {
+		return fmt.Errorf("%w: \"%s\"", ErrInvalidPackageName, name)
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package npm
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseSpec(t *testing.T) {
+	tests := []struct {
+		input     string
+		name      string
+		rawSpec   string
+		specType  SpecType
+		fetchSpec string
+		// registryName is the name of the package fetched from the registry,
+		// which differs from name for aliases
+		registryName string
+		wantError    error
+	}{
+		{input: "react", name: "react", specType: SpecTypeTag, fetchSpec: DefaultDistTag},
+		{input: "react@18.3.1", name: "react", rawSpec: "18.3.1", specType: SpecTypeVersion, fetchSpec: "18.3.1"},
+		{input: "react@v18.3.1", name: "react", rawSpec: "v18.3.1", specType: SpecTypeVersion, fetchSpec: "18.3.1"},
+		{input: "react@^18", name: "react", rawSpec: "^18", specType: SpecTypeRange, fetchSpec: "^18"},
+		{input: "react@>=16 <19", name: "react", rawSpec: ">=16 <19", specType: SpecTypeRange, fetchSpec: ">=16 <19"},
+		{input: "react ^18", name: "react", rawSpec: "^18", specType: SpecTypeRange, fetchSpec: "^18"},
+		{input: "typescript@next", name: "typescript", rawSpec: "next", specType: SpecTypeTag, fetchSpec: "next"},
+		{input: "@types/node", name: "@types/node", specType: SpecTypeTag, fetchSpec: DefaultDistTag},
+		{input: "@types/node@20.1.0", name: "@types/node", rawSpec: "20.1.0", specType: SpecTypeVersion, fetchSpec: "20.1.0"},
+		{input: "@types/node@~20.1", name: "@types/node", rawSpec: "~20.1", specType: SpecTypeRange, fetchSpec: "~20.1"},
+		{input: "my-react@npm:react@18", name: "my-react", rawSpec: "npm:react@18", specType: SpecTypeAlias, registryName: "react"},
+		{input: "node-types@npm:@types/node@beta", name: "node-types", rawSpec: "npm:@types/node@beta", specType: SpecTypeAlias, registryName: "@types/node"},
+		{input: "a@npm:b@npm:c@1", wantError: ErrInvalidSpec},
+		{input: "react@not a tag", wantError: ErrInvalidSpec},
+		{input: "@types", wantError: ErrInvalidPackageName},
+		{input: "_private@1.0.0", wantError: ErrInvalidPackageName},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			s, err := ParseSpec(test.input)
+			if test.wantError != nil {
+				if !errors.Is(err, test.wantError) {
+					t.Fatalf("error = %v, want %v", err, test.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if s.Name != test.name || s.RawSpec != test.rawSpec || s.Type != test.specType {
+				t.Errorf("spec = %s %q (%s), want %s %q (%s)", s.Name, s.RawSpec, s.Type, test.name, test.rawSpec, test.specType)
+			}
+			if test.fetchSpec != "" && s.FetchSpec != test.fetchSpec {
+				t.Errorf("fetch spec = %q, want %q", s.FetchSpec, test.fetchSpec)
+			}
+
+			registryName := test.registryName
+			if registryName == "" {
+				registryName = test.name
+			}
+			if s.RegistryName() != registryName {
+				t.Errorf("registry name = %s, want %s", s.RegistryName(), registryName)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"package_size_calculator/pkg/npm"
+	"sync"
+
+	"github.com/dustin/go-humanize"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// defaultComparedPlatforms are the platforms most users install packages on
+var defaultComparedPlatforms = []string{
+	"linux/x64/glibc",
+	"linux/x64/musl",
+	"linux/arm64/glibc",
+	"darwin/x64",
+	"darwin/arm64",
+	"win32/x64",
+}
+
+type platformStats struct {
+	Platform        npm.Platform
+	Size            uint64
+	Subdependencies uint64
+	Err             error
+}
+
+// comparePlatforms measures a package on several platforms, which differ in
+// the optional dependencies that get installed, e.g. prebuilt binaries.
+func comparePlatforms(rawPlatforms []string) {
+	if len(rawPlatforms) == 0 {
+		rawPlatforms = defaultComparedPlatforms
+	}
+
+	platforms := make([]npm.Platform, 0, len(rawPlatforms))
+	for _, raw := range rawPlatforms {
+		p, err := npm.ParsePlatform(raw)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to parse platform")
+		}
+
+		platforms = append(platforms, p)
+	}
+
+	packageInfo, version := promptPackageSpec(npmClient)
+	if version == nil {
+		v := packageInfo.Versions[promptPackageVersion(packageInfo, "Select version")]
+		version = &v
+	}
+
+	downloads, err := npmClient.GetPackageDownloadsLastWeek(packageInfo.Name)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to fetch package downloads")
+	}
+
+	var downloadsLastWeek *uint64
+	if dls, ok := downloads.ForVersion(version.Version.String()); ok {
+		downloadsLastWeek = &dls
+	}
+
+	results := make([]platformStats, len(platforms))
+	wg := sync.WaitGroup{}
+	for i, p := range platforms {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			results[i] = measurePlatform(version.JSON.AsDependency(), p)
+		}()
+	}
+	wg.Wait()
+
+	err = writeTextReport(func(w io.Writer) { reportPlatforms(w, version.JSON, results, downloadsLastWeek) })
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to write report")
+	}
+}
+
+func measurePlatform(package_ npm.DependencyInfo, platform npm.Platform) platformStats {
+	s := platformStats{Platform: platform}
+
+	opts := defaultInstallOptions()
+	opts.Platform = &platform
+
+	size, tmpDir, err := measurePackageSizeWith(package_, opts)
+	if tmpDir != "" && !*fNoCleanup {
+		defer tmpDir.Remove()
+	}
+	if err != nil {
+		s.Err = err
+		return s
+	}
+	s.Size = size
+
+	lock, err := npm.ParsePackageLockJSON(tmpDir.Join("package-lock.json"))
+	if err != nil {
+		s.Err = errors.Wrap(err, "failed to parse package-lock.json")
+		return s
+	}
+
+	// Optional dependencies for other platforms are locked, but not installed
+	sizes, err := measureLockedPackageSizes(tmpDir.String(), lock)
+	if err != nil {
+		s.Err = errors.Wrap(err, "failed to measure installed packages")
+		return s
+	}
+	// The sizes include the measured package itself, unless nothing was
+	// installed for the platform
+	if len(sizes) > 0 {
+		s.Subdependencies = uint64(len(sizes) - 1)
+	}
+
+	return s
+}
+
+func reportPlatforms(w io.Writer, pkg npm.PackageJSON, results []platformStats, downloadsLastWeek *uint64) {
+	fmt.Fprintln(w)
+	boldGreen.Fprintln(w, "Platform size report")
+	boldGreen.Fprintln(w, "====================")
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%s\n", bold.Sprintf("Sizes of \"%s\" per platform", boldYellow.Sprint(pkg.String())))
+	if downloadsLastWeek != nil {
+		fmt.Fprintln(w, gray.Sprintf("Traffic assumes all %s downloads last week came from the platform", fmtInt(int64(*downloadsLastWeek))))
+	}
+
+	// Differences are relative to the first platform that could be measured
+	reference := -1
+	for i, r := range results {
+		if r.Err == nil {
+			reference = i
+			break
+		}
+	}
+
+	for i, r := range results {
+		fmt.Fprintln(w)
+		if r.Err != nil {
+			fmt.Fprintf(w, "  %s: %s %s\n", boldYellow.Sprint(r.Platform), boldRed.Sprint("failed"), grayParens("%s", r.Err))
+			continue
+		}
+
+		delta := ""
+		if i != reference {
+			ref := results[reference]
+			delta = " " + grayParens("%s vs %s", fmtSignedBytes(int64(r.Size)-int64(ref.Size)), ref.Platform)
+		}
+
+		fmt.Fprintf(w, "  %s: %s%s\n", boldYellow.Sprint(r.Platform), humanize.Bytes(r.Size), delta)
+		fmt.Fprintf(w, "    %s: %s\n", bold.Sprint("Subdependencies"), fmtInt(int64(r.Subdependencies)))
+
+		if downloadsLastWeek == nil {
+			continue
+		}
+
+		traffic := big.NewInt(0).Mul(big.NewInt(int64(*downloadsLastWeek)), big.NewInt(int64(r.Size)))
+		trafficDelta := ""
+		if i != reference {
+			difference := big.NewInt(0).Mul(big.NewInt(int64(*downloadsLastWeek)), big.NewInt(int64(r.Size)-int64(results[reference].Size)))
+			trafficDelta = " " + grayParens("%s", fmtSignedBigBytes(difference))
+		}
+
+		fmt.Fprintf(w, "    %s: %s%s\n", bold.Sprint("Traffic last week"), humanize.BigBytes(traffic), trafficDelta)
+	}
+}
+
+func fmtSignedBigBytes(delta *big.Int) string {
+	c := deltaColor(int64(delta.Sign()))
+
+	if delta.Sign() < 0 {
+		return c.Sprintf("-%s", humanize.BigBytes(big.NewInt(0).Neg(delta)))
+	}
+
+	return c.Sprintf("+%s", humanize.BigBytes(delta))
+}
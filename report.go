@@ -2,14 +2,16 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"math/big"
+	"os"
 	"package_size_calculator/pkg/npm"
-	"package_size_calculator/pkg/time_helpers"
-	"strings"
 	"time"
 
 	"github.com/dustin/go-humanize"
 	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
 )
 
 var (
@@ -26,267 +28,622 @@ var (
 type ModifiedStats struct {
 	Size            uint64
 	Subdependencies uint64
+	Kinds           kindBreakdown
+	// InstallScripts is the number of packages with install scripts
+	InstallScripts uint64
+	// Deprecated is the number of deprecated packages
+	Deprecated uint64
+	Licenses   licenseInventory
+	// Tree is only set for the HTML report
+	Tree *sizeTree
 }
 
-func printReport(
+const (
+	formatText = "text"
+	formatHTML = "html"
+	formatSVG  = "svg"
+	formatPNG  = "png"
+	formatCSV  = "csv"
+	formatTSV  = "tsv"
+	formatJSON = "json"
+)
+
+// defaultOutputs are the files reports are written to without -output. Text
+// reports are printed instead.
+var defaultOutputs = map[string]string{
+	formatHTML: "report.html",
+	formatSVG:  "report.svg",
+	formatPNG:  "report.png",
+	formatCSV:  "report.csv",
+	formatTSV:  "report.tsv",
+	formatJSON: "report.json",
+}
+
+// appendedFormats are appended to existing output files instead of
+// overwriting them, so that batch runs collect all packages in one file
+var appendedFormats = map[string]bool{
+	formatCSV: true,
+	formatTSV: true,
+}
+
+// Renderer shows a report in one format.
+type Renderer interface {
+	Render(w io.Writer, r *Report) error
+}
+
+// Report is everything a size report shows, calculated once from the
+// measurements so that every renderer shows the same numbers.
+type Report struct {
+	// Title is empty for reports without a header
+	Title       string
+	GeneratedAt time.Time
+	// Packages are the measured packages, e.g. the old and new version
+	Packages []packageSummary
+	Removed  []dependencySummary
+	// ExclusiveOfRemoved is only set if more than one dependency was removed
+	ExclusiveOfRemoved *exclusiveSummary
+	Added              []dependencySummary
+	Overrides          []overrideSummary
+	Statistics         statisticsChange
+	// Kinds is empty if only production dependencies are installed
+	Kinds []kindChange
+	// Counts are the numbers of packages of some sort before and after the
+	// modification, only if there are any
+	Counts   []countChange
+	Licenses []licenseChange
+	// EgressPrice is the price the traffic costs are estimated with, nil if
+	// they aren't estimated
+	EgressPrice *egressPrice
+	// Energy is only set if it was requested with -energy
+	Energy *energyChange
+	// Trees are the installed trees, only measured for the HTML report
+	Trees []treeView
+}
+
+type packageSummary struct {
+	// Name is the package with its version, e.g. "lodash@4.17.21"
+	Name        string
+	PackageName string
+	Version     string
+	ReleaseTime time.Time
+	Stats       calculatedStats
+	// Estimate is only set if it was requested with -compare-estimate
+	Estimate *estimateSummary
+	// Kinds is empty if only production dependencies are installed
+	Kinds          []kindSummary
+	InstallScripts []installScript
+	// ScriptsSize is the size install scripts add, only set if it was
+	// requested with -compare-scripts
+	ScriptsSize *int64
+	Deprecated  []npm.DeprecatedPackage
+	Licenses    []licenseSummary
+	// LatestVersion is only set if the package isn't the latest version
+	LatestVersion *versionSummary
+}
+
+type estimateSummary struct {
+	Size              uint64
+	Subdependencies   uint64
+	PercentOfMeasured float64
+}
+
+type kindSummary struct {
+	Kind npm.DependencyKind
+	kindStats
+}
+
+type licenseSummary struct {
+	License  npm.License
+	Status   licenseStatus
+	Packages []string
+}
+
+type versionSummary struct {
+	Version     string
+	ReleaseTime time.Time
+}
+
+// dependencySummary is a removed or added dependency. The percentages are
+// relative to the modified package.
+type dependencySummary struct {
+	// Name is the dependency with its spec, e.g. "lodash@^4.17.21"
+	Name        string
+	PackageName string
+	Version     string
+	// SpecType is only set for dependencies that aren't installed from a
+	// registry version or range, e.g. git repositories or aliases
+	SpecType                 string
+	Stats                    calculatedStats
+	PercentOfSize            float64
+	PercentOfSubdependencies float64
+	// PercentOfTraffic is nil if the downloads are unknown
+	PercentOfTraffic *float64
+	// Exclusive is only set for removed dependencies
+	Exclusive  *exclusiveSummary
+	Deprecated []npm.DeprecatedPackage
+}
+
+type exclusiveSummary struct {
+	exclusiveStats
+	PercentOfSize float64
+}
+
+// overrideSummary is the change an override makes compared to the unmodified
+// package.
+type overrideSummary struct {
+	Name                        string
+	SizeDelta                   int64
+	SubdependenciesDelta        int64
+	PercentSizeDelta            float64
+	PercentSubdependenciesDelta float64
+}
+
+type statisticsChange struct {
+	OldSize     uint64
+	NewSize     uint64
+	PercentSize float64
+
+	OldSubdependencies uint64
+	NewSubdependencies uint64
+
+	// Traffic is the traffic of the version with last week's downloads
+	Traffic trafficChange
+	// TrafficAllVersions is the traffic if all of last week's downloads
+	// were of the version, or the total traffic of the version mix. Only the
+	// measured version changes in a mix, so it has no projections then.
+	TrafficAllVersions trafficChange
+	// VersionMix is only set if it was requested with -version-mix
+	VersionMix *versionMix
+}
+
+type trafficChange struct {
+	// Old and New are nil if the downloads are unknown
+	Old *big.Int
+	New *big.Int
+	// Projections are the saved traffic over longer periods
+	Projections []trafficProjection
+}
+
+// AllVersionsSavings is the change whose savings stand for all versions. With
+// a version mix, the other versions don't change, so all versions save as much
+// as the current version.
+func (s statisticsChange) AllVersionsSavings() trafficChange {
+	if s.VersionMix != nil {
+		return s.Traffic
+	}
+
+	return s.TrafficAllVersions
+}
+
+// Saved is how much less traffic there is after the modification, negative
+// if there is more.
+func (t trafficChange) Saved() *big.Int {
+	return new(big.Int).Sub(t.Old, t.New)
+}
+
+type kindChange struct {
+	Kind npm.DependencyKind
+	Old  kindStats
+	New  kindStats
+}
+
+type countChange struct {
+	Label string
+	Old   uint64
+	New   uint64
+}
+
+type licenseChange struct {
+	License npm.License
+	Status  licenseStatus
+	// Added is false for licenses that the modification removes
+	Added    bool
+	Packages []string
+}
+
+// treeView is one of the installed trees shown in the treemap, e.g. the tree
+// before or after a modification.
+type treeView struct {
+	Name string    `json:"name"`
+	Tree *sizeTree `json:"tree"`
+}
+
+func newReplacementReport(
 	pkg *packageInfo,
 	statistics *ModifiedStats,
 	removedDependencies []npm.DependencyInfo,
 	addedDependencies []npm.PackageJSON,
 	deps map[string]*dependencyPackageInfo,
-) {
-	package_ := pkg.Package
-	packageJson := package_.JSON
-	downloadsLastWeek := pkg.Stats.DownloadsLastWeek
+	baseline *ModifiedStats,
+	overrides []*overrideInfo,
+) *Report {
 	oldPackageSize := pkg.Stats.Size
 	oldSubdependencies := getSubdependenciesCount(pkg.Lockfile)
 
-	modifiedPackageName := boldYellow.Sprint(packageJson.String())
+	r := &Report{
+		Title:       "Package size report",
+		GeneratedAt: time.Now(),
+		Packages:    []packageSummary{summarizePackage(pkg, true)},
+		EgressPrice: activeEgressPrice,
+	}
 
-	fmt.Println()
-	boldGreen.Println("Package size report")
-	boldGreen.Println("===================")
+	for _, p := range removedDependencies {
+		r.Removed = append(r.Removed, summarizeDependency(deps[p.String()], oldPackageSize, oldSubdependencies))
+	}
+	if len(removedDependencies) > 1 {
+		exclusive := summarizeExclusive(pkg.ExclusiveOfRemoved, oldPackageSize)
+		r.ExclusiveOfRemoved = &exclusive
+	}
 
-	fmt.Println()
-	reportPackageInfo(pkg, true, 0)
+	for _, p := range addedDependencies {
+		r.Added = append(r.Added, summarizeDependency(deps[p.String()], oldPackageSize, oldSubdependencies))
+	}
 
-	if len(removedDependencies) > 0 {
-		fmt.Println()
-		if *fShortMode {
-			color.Red("Removed deps:")
-		} else {
-			color.Red("Removed dependencies:")
-		}
+	for _, o := range overrides {
+		sizeDelta := int64(o.Size) - int64(baseline.Size)
+		subdepsDelta := int64(o.Subdependencies) - int64(baseline.Subdependencies)
+
+		r.Overrides = append(r.Overrides, overrideSummary{
+			Name:                        o.String(),
+			SizeDelta:                   sizeDelta,
+			SubdependenciesDelta:        subdepsDelta,
+			PercentSizeDelta:            calculatePercentage(float64(sizeDelta), float64(baseline.Size)),
+			PercentSubdependenciesDelta: calculatePercentage(float64(subdepsDelta), float64(baseline.Subdependencies)),
+		})
+	}
 
-		for _, p := range removedDependencies {
-			stats := deps[p.String()]
+	r.Statistics = newStatisticsChange(
+		oldPackageSize,
+		statistics.Size,
+		pkg.Stats.DownloadsLastWeek,
+		pkg.Stats.TotalDownloads,
+		oldSubdependencies,
+		statistics.Subdependencies,
+		pkg.VersionMix,
+	)
+	r.Energy = newEnergyChange(r.Statistics, activeEnergyModel)
+	r.Kinds = newKindChanges(pkg.Kinds, statistics.Kinds)
+	r.Counts = newCountChanges(
+		countChange{Label: "Packages with install scripts", Old: countOtherInstallScripts(pkg.InstallScripts, pkg.Package.JSON.Name), New: statistics.InstallScripts},
+		countChange{Label: "Deprecated packages", Old: countOtherDeprecated(pkg.Deprecated, pkg.Package.JSON.Name), New: statistics.Deprecated},
+	)
+	r.Licenses = newLicenseChanges(pkg.Licenses, statistics.Licenses)
+	r.Trees = newTreeViews(treeView{Name: "Before", Tree: pkg.Tree}, treeView{Name: "After", Tree: statistics.Tree})
+
+	return r
+}
+
+func newVersionReport(pkg *packageVersionsInfo) *Report {
+	r := &Report{
+		GeneratedAt: time.Now(),
+		Packages:    []packageSummary{summarizePackage(&pkg.Old, false), summarizePackage(&pkg.New, false)},
+		EgressPrice: activeEgressPrice,
+	}
 
-			pcDLs := stats.FormattedPercentDownloadsOfVersion()
-			pcSize := float64(stats.Size) * 100 / float64(oldPackageSize)
-			pcTrafficOfPackageFmt := stats.FormattedPercentOfPackageTraffic(oldPackageSize)
-			pcSubdeps := stats.PercentOfPackageSubdependencies(oldSubdependencies)
+	r.Statistics = newStatisticsChange(
+		pkg.Old.Stats.Size,
+		pkg.New.Stats.Size,
+		pkg.Old.Stats.DownloadsLastWeek,
+		pkg.New.Stats.TotalDownloads,
+		pkg.Old.Stats.Subdependencies,
+		pkg.New.Stats.Subdependencies,
+		pkg.New.VersionMix,
+	)
+	r.Energy = newEnergyChange(r.Statistics, activeEnergyModel)
+	r.Kinds = newKindChanges(pkg.Old.Kinds, pkg.New.Kinds)
+	r.Licenses = newLicenseChanges(pkg.Old.Licenses, pkg.New.Licenses)
+	r.Trees = newTreeViews(treeView{Name: pkg.Old.String(), Tree: pkg.Old.Tree}, treeView{Name: pkg.New.String(), Tree: pkg.New.Tree})
 
-			upperDLsFmt := pkg.Stats.FormattedDownloadsLastWeek()
-			upperDLsTrafficFmt := pkg.Stats.FormattedTrafficLastWeek()
+	return r
+}
 
-			dlsFmt := stats.FormattedDownloadsLastWeek()
-			trafficFmt := stats.FormattedTrafficLastWeek()
+func summarizePackage(b *packageInfo, showLatestVersionHint bool) packageSummary {
+	s := packageSummary{
+		Name:           b.String(),
+		PackageName:    b.Package.JSON.Name,
+		Version:        b.Package.Version.String(),
+		ReleaseTime:    b.Package.ReleaseTime,
+		Stats:          b.Stats,
+		InstallScripts: b.InstallScripts,
+		Deprecated:     b.Deprecated,
+		Licenses:       summarizeLicenses(b.Licenses),
+	}
 
-			if *fShortMode {
-				fmt.Printf("  %s %s: %s\n", color.RedString("-"), boldYellow.Sprint(p.String()), humanize.Bytes(stats.Size))
-				fmt.Printf(
-					"    %s: %s %s\n",
-					bold.Sprint("DLs last week"),
-					dlsFmt,
-					grayParens("%s", trafficFmt),
-				)
+	if b.Estimate != nil {
+		s.Estimate = &estimateSummary{
+			Size:              b.Estimate.Size,
+			Subdependencies:   b.Estimate.Subdependencies,
+			PercentOfMeasured: calculatePercentage(float64(b.Estimate.Size), float64(b.Stats.Size)),
+		}
+	}
 
-				continue
+	if b.Kinds.HasNonProd() {
+		for _, kind := range npm.DependencyKinds {
+			if k, ok := b.Kinds[kind]; ok {
+				s.Kinds = append(s.Kinds, kindSummary{Kind: kind, kindStats: k})
 			}
+		}
+	}
+
+	if b.SizeWithoutScripts != nil {
+		scriptsSize := int64(b.Stats.Size) - int64(*b.SizeWithoutScripts)
+		s.ScriptsSize = &scriptsSize
+	}
 
-			fmt.Printf(
-				"  %s %s: %s %s\n",
-				color.RedString("-"),
-				boldYellow.Sprint(p.String()),
-				humanize.Bytes(stats.Size),
-				grayParens("%s%%", fmtPercent(pcSize)),
-			)
-			fmt.Printf(
-				"    %s: %s %s\n",
-				bold.Sprint("Downloads last week"),
-				dlsFmt,
-				grayParens("%s%% from %s", pcDLs, boldYellow.Sprint(stats.Version)),
-			)
-			fmt.Printf(
-				"    %s: %s %s\n",
-				bold.Sprintf("Downloads last week from \"%s\"", modifiedPackageName),
-				upperDLsFmt,
-				grayParens("%s%%", pcTrafficOfPackageFmt),
-			)
-			fmt.Printf("    %s: %s\n", bold.Sprint("Traffic last week"), trafficFmt)
-			fmt.Printf("    %s: %s %s\n",
-				bold.Sprintf("Traffic from \"%s\"", modifiedPackageName),
-				upperDLsTrafficFmt,
-				grayParens("%s%%", pcTrafficOfPackageFmt),
-			)
-			fmt.Printf(
-				"    %s: %s %s\n",
-				bold.Sprint("Subdependencies"),
-				stats.FormattedSubdependencies(),
-				grayParens("%s%%", fmtPercent(pcSubdeps)),
-			)
+	if showLatestVersionHint {
+		latestVersion := b.Info.LatestVersion
+		if b.Package.JSON.Version != latestVersion.JSON.Version {
+			s.LatestVersion = &versionSummary{Version: latestVersion.Version.String(), ReleaseTime: latestVersion.ReleaseTime}
 		}
 	}
 
-	if len(addedDependencies) > 0 {
-		fmt.Println()
-		color.Green("Added dependencies:")
+	return s
+}
+
+func summarizeDependency(d *dependencyPackageInfo, outerSize, outerSubdependencies uint64) dependencySummary {
+	s := dependencySummary{
+		Name:                     d.String(),
+		PackageName:              d.Name,
+		Version:                  d.Version,
+		Stats:                    d.calculatedStats,
+		PercentOfSize:            d.PercentOfPackageSize(outerSize),
+		PercentOfSubdependencies: d.PercentOfPackageSubdependencies(outerSubdependencies),
+		Deprecated:               d.Deprecated,
+	}
+
+	if d.Spec != nil {
+		s.SpecType = d.SpecType().String()
+	}
 
-		for _, p := range addedDependencies {
-			info := deps[p.String()]
+	if d.TrafficLastWeek != nil {
+		pc := calculatePercentage(float64(*d.TrafficLastWeek), float64(outerSize))
+		s.PercentOfTraffic = &pc
+	}
 
-			pcDLs := info.FormattedPercentDownloadsOfVersion()
-			pcSize := info.PercentOfPackageSize(oldPackageSize)
-			pcSubdeps := info.PercentOfPackageSubdependencies(oldSubdependencies)
+	if d.Exclusive != nil {
+		exclusive := summarizeExclusive(*d.Exclusive, outerSize)
+		s.Exclusive = &exclusive
+	}
+
+	return s
+}
 
-			dlsFmt := info.FormattedDownloadsLastWeek()
-			trafficFmt := info.FormattedTrafficLastWeek()
+func summarizeExclusive(e exclusiveStats, outerSize uint64) exclusiveSummary {
+	return exclusiveSummary{
+		exclusiveStats: e,
+		PercentOfSize:  calculatePercentage(float64(e.Size), float64(outerSize)),
+	}
+}
 
-			if *fShortMode {
-				fmt.Printf("  %s %s: %s\n", color.GreenString("+"), boldYellow.Sprint(p.String()), humanize.Bytes(info.Size))
-				fmt.Printf("    %s: %s\n", bold.Sprint("DLs last week"), dlsFmt)
+func newStatisticsChange(oldSize, newSize uint64, downloads *uint64, totalDownloads, oldSubdependencies, newSubdependencies uint64, mix *versionMix) statisticsChange {
+	s := statisticsChange{
+		OldSize:            oldSize,
+		NewSize:            newSize,
+		PercentSize:        calculatePercentage(float64(newSize), float64(oldSize)),
+		OldSubdependencies: oldSubdependencies,
+		NewSubdependencies: newSubdependencies,
+		Traffic:            newTrafficChange(downloads, oldSize, newSize),
+		TrafficAllVersions: newTrafficChange(&totalDownloads, oldSize, newSize),
+		VersionMix:         mix,
+	}
+	if mix != nil {
+		s.TrafficAllVersions = newMixedTrafficChange(mix, downloads, oldSize, newSize)
+	}
 
-				continue
-			}
+	return s
+}
+
+func newTrafficChange(downloads *uint64, oldSize, newSize uint64) trafficChange {
+	if downloads == nil {
+		return trafficChange{}
+	}
+
+	d := new(big.Int).SetUint64(*downloads)
+
+	t := trafficChange{
+		Old: new(big.Int).Mul(d, new(big.Int).SetUint64(oldSize)),
+		New: new(big.Int).Mul(d, new(big.Int).SetUint64(newSize)),
+	}
+	t.Projections = projectTraffic(t.Saved(), activeEgressPrice)
+
+	return t
+}
+
+// newKindChanges compares the installed dependencies of each kind. Nothing is
+// compared if only production dependencies are installed.
+func newKindChanges(oldKinds, newKinds kindBreakdown) []kindChange {
+	if !oldKinds.HasNonProd() && !newKinds.HasNonProd() {
+		return nil
+	}
+
+	var changes []kindChange
+	for _, kind := range npm.DependencyKinds {
+		oldStats, oldOk := oldKinds[kind]
+		newStats, newOk := newKinds[kind]
+		if !oldOk && !newOk {
+			continue
+		}
+
+		changes = append(changes, kindChange{Kind: kind, Old: oldStats, New: newStats})
+	}
 
-			fmt.Printf(
-				"  %s %s: %s %s\n",
-				color.GreenString("+"),
-				boldYellow.Sprint(p.String()),
-				humanize.Bytes(info.Size),
-				grayParens("%s%%", fmtPercent(pcSize)),
-			)
-			fmt.Printf(
-				"    %s: %s %s\n",
-				bold.Sprint("Downloads last week"),
-				dlsFmt,
-				grayParens("%s%% from %s", pcDLs, boldYellow.Sprint(info.Version)),
-			)
-			fmt.Printf("    %s: %s\n", bold.Sprint("Estimated traffic last week"), trafficFmt)
-			fmt.Printf(
-				"    %s: %s %s\n",
-				bold.Sprint("Subdependencies"),
-				info.FormattedSubdependencies(),
-				grayParens("%s%%", fmtPercent(pcSubdeps)),
-			)
+	return changes
+}
+
+// newCountChanges leaves out the counts that are zero before and after the
+// modification.
+func newCountChanges(counts ...countChange) []countChange {
+	var changes []countChange
+	for _, c := range counts {
+		if c.Old != 0 || c.New != 0 {
+			changes = append(changes, c)
 		}
 	}
 
-	fmt.Println()
-	reportEstimatedStatistics(oldPackageSize, statistics.Size, downloadsLastWeek, pkg.Stats.TotalDownloads, oldSubdependencies, statistics.Subdependencies)
+	return changes
 }
 
-func reportPackageInfo(modifiedPackage *packageInfo, showLatestVersionHint bool, indentation int) {
-	indent := strings.Repeat(" ", indentation)
+// newTreeViews leaves out the trees that weren't measured.
+func newTreeViews(views ...treeView) []treeView {
+	var measured []treeView
+	for _, v := range views {
+		if v.Tree != nil {
+			measured = append(measured, v)
+		}
+	}
 
-	packageInfo := modifiedPackage.Info
-	package_ := modifiedPackage.Package
-	packageJson := package_.JSON
-	oldPackageSize := modifiedPackage.Stats.Size
-	dlsFmt := modifiedPackage.Stats.FormattedPercentDownloadsOfVersion()
+	return measured
+}
 
-	modifiedPackageName := boldYellow.Sprint(packageJson.String())
+// newRenderer selects the renderer for the -format and -short flags.
+func newRenderer() Renderer {
+	switch *fFormat {
+	case formatHTML:
+		return htmlRenderer{}
+	case formatSVG:
+		return svgCardRenderer{}
+	case formatPNG:
+		return pngCardRenderer{}
+	case formatCSV:
+		return csvRenderer{Comma: ','}
+	case formatTSV:
+		return csvRenderer{Comma: '\t'}
+	case formatJSON:
+		return jsonRenderer{}
+	}
 
 	if *fShortMode {
-		fmt.Printf("%s%s: %s\n", indent, modifiedPackageName, humanize.Bytes(oldPackageSize))
-		fmt.Printf("%s  %s: %s ago\n", indent, bold.Sprint("Released"), time_helpers.FormatDuration(time.Since(package_.ReleaseTime)))
-		fmt.Printf("%s  %s: %s\n", indent, bold.Sprint("DLs last week"), dlsFmt)
+		return shortTextRenderer{}
+	}
 
-		return
+	return longTextRenderer{}
+}
+
+// writeReport renders the report to the file given with -output, or to
+// stdout for text reports without one.
+func writeReport(r *Report) error {
+	path := *fOutput
+	if path == "" {
+		path = defaultOutputs[*fFormat]
 	}
 
-	fmt.Printf("%s%s: %s\n", indent, bold.Sprintf("Package info for \"%s\"", modifiedPackageName), humanize.Bytes(oldPackageSize))
-	fmt.Printf(
-		"%s  %s: %s %s\n",
-		indent,
-		bold.Sprint("Released"),
-		package_.ReleaseTime,
-		grayParens("%s ago", time_helpers.FormatDuration(time.Since(package_.ReleaseTime))),
-	)
+	renderer := newRenderer()
+	w := io.Writer(os.Stdout)
+	if path != "" {
+		flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+		if appendedFormats[*fFormat] {
+			flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+		}
 
-	fmt.Printf(
-		"%s  %s: %s %s\n",
-		indent,
-		bold.Sprint("Downloads last week"),
-		modifiedPackage.Stats.FormattedDownloadsLastWeek(),
-		grayParens("%s%%", dlsFmt),
-	)
-	fmt.Printf("%s  %s: %s\n", indent, bold.Sprint("Estimated traffic last week"), modifiedPackage.Stats.FormattedTrafficLastWeek())
-	fmt.Printf("%s  %s: %s\n", indent, bold.Sprint("Subdependencies"), modifiedPackage.Stats.FormattedSubdependencies())
+		f, err := os.OpenFile(path, flags, 0o644)
+		if err != nil {
+			return errors.Wrap(err, "failed to create report file")
+		}
+		defer f.Close()
 
-	if showLatestVersionHint {
-		latestVersion := packageInfo.LatestVersion
-		if packageJson.Version != latestVersion.JSON.Version {
-			fmt.Printf("%s  %s: %s %s\n",
-				indent,
-				bold.Sprint("Latest version"),
-				latestVersion.Version,
-				grayParens("%s ago", time_helpers.FormatDuration(time.Since(latestVersion.ReleaseTime))),
-			)
+		// The header is already in files that earlier runs appended to
+		if c, ok := renderer.(csvRenderer); ok {
+			info, err := f.Stat()
+			if err != nil {
+				return errors.Wrap(err, "failed to stat report file")
+			}
+
+			c.SkipHeader = info.Size() > 0
+			renderer = c
 		}
+
+		w = f
+	}
+
+	if err := renderer.Render(w, r); err != nil {
+		return errors.Wrap(err, "failed to render report")
+	}
+
+	if path != "" {
+		log.Info().Str("path", path).Msg("Wrote report")
 	}
+
+	return nil
 }
 
-func reportEstimatedStatistics(oldSize, newSize uint64, downloads *uint64, totalDownloads, oldSubdependencies, newSubdependencies uint64) {
-	indicatorColor := boldGreen
-	if newSize > oldSize {
-		indicatorColor = boldRed
-	} else if newSize == oldSize {
-		indicatorColor = boldGray
+// requireTextFormat stops commands whose reports only exist as text, instead of
+// silently ignoring -format.
+func requireTextFormat(command string) {
+	if *fFormat != formatText {
+		log.Fatal().Str("command", command).Str("format", *fFormat).Msg("Command only supports text reports")
+	}
+}
+
+// writeTextReport writes the text report of a command to the file given with
+// -output, or prints it if there is none.
+func writeTextReport(render func(w io.Writer)) error {
+	out := io.Writer(os.Stdout)
+	if *fOutput != "" {
+		f, err := os.Create(*fOutput)
+		if err != nil {
+			return errors.Wrap(err, "failed to create report file")
+		}
+		defer f.Close()
+
+		out = f
 	}
 
-	pcSize := calculatePercentage(float64(newSize), float64(oldSize))
-	pcSizeFmt := indicatorColor.Sprintf("%s%%", fmtPercent(pcSize))
+	w := &errWriter{w: out}
+	render(w)
+	if w.err != nil {
+		return errors.Wrap(w.err, "failed to write report")
+	}
 
-	oldTrafficLastWeekFmt, estNewTrafficFmt, estTrafficChangeFmt := formattedTraffic(downloads, oldSize, newSize)
-	scaledOldTrafficLastWeekFmt, scaledEstTrafficNextWeekFmt, scaledEstTrafficChangeFmt := formattedTraffic(&totalDownloads, oldSize, newSize)
-	oldSubdepsFmt, estSubdepsFmt, subdepsChangeFmt := reportSubdependencies(oldSubdependencies, newSubdependencies)
+	if *fOutput != "" {
+		log.Info().Str("path", *fOutput).Msg("Wrote report")
+	}
 
-	if *fShortMode {
-		fmt.Printf(
-			"%s: %s %s %s %s\n",
-			bold.Sprint("Est. size"),
-			humanize.Bytes(oldSize),
-			arrow,
-			indicatorColor.Sprintf(humanize.Bytes(newSize)),
-			grayParens("%s", pcSizeFmt),
-		)
-		fmt.Printf(
-			"%s: %s %s %s %s\n",
-			bold.Sprint("Est. traffic"),
-			oldTrafficLastWeekFmt,
-			arrow,
-			indicatorColor.Sprint(estNewTrafficFmt),
-			grayParens("%s", estTrafficChangeFmt),
-		)
-
-		return
-	}
-
-	bold.Println("Estimated new statistics:")
-	fmt.Printf(
-		"  %s: %s %s %s %s\n",
-		bold.Sprint("Package size"),
-		humanize.Bytes(oldSize),
-		arrow,
-		indicatorColor.Sprintf(humanize.Bytes(newSize)),
-		grayParens("%s", pcSizeFmt),
-	)
-	fmt.Printf(
-		"  %s: %s %s %s %s\n",
-		bold.Sprint("Subdependencies"),
-		oldSubdepsFmt,
-		arrow,
-		estSubdepsFmt,
-		grayParens("%s", subdepsChangeFmt),
-	)
-	bold.Println("  Traffic with last week's downloads:")
-	fmt.Printf(
-		"    %s: %s %s %s %s\n",
-		bold.Sprint("For current version"),
-		oldTrafficLastWeekFmt,
-		arrow,
-		estNewTrafficFmt,
-		grayParens("%s", estTrafficChangeFmt),
-	)
-	fmt.Printf(
-		"    %s: %s %s %s %s\n",
-		bold.Sprint("For all versions"),
-		scaledOldTrafficLastWeekFmt,
-		arrow,
-		indicatorColor.Sprint(scaledEstTrafficNextWeekFmt),
-		grayParens("%s", scaledEstTrafficChangeFmt),
-	)
+	return nil
+}
+
+// errWriter keeps the first error of the underlying writer, so that renderers
+// only have to check for errors once.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (e *errWriter) Write(p []byte) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+
+	var n int
+	n, e.err = e.w.Write(p)
+
+	return n, e.err
+}
+
+// reportSubject names the measured package, or the old and new version.
+func reportSubject(r *Report) string {
+	switch len(r.Packages) {
+	case 1:
+		return r.Packages[0].Name
+	case 2:
+		return fmt.Sprintf("%s → %s", r.Packages[0].Name, r.Packages[1].Name)
+	}
+
+	return ""
+}
+
+func kindLabel(kind npm.DependencyKind) string {
+	switch kind {
+	case npm.DependencyKindProd:
+		return "Production dependencies"
+	case npm.DependencyKindDev:
+		return "Dev dependencies"
+	case npm.DependencyKindOptional:
+		return "Optional dependencies"
+	case npm.DependencyKindPeer:
+		return "Peer dependencies"
+	}
+
+	return kind.String()
 }
 
 func reportSubdependencies(oldSubdependencies, newSubdependencies uint64) (string, string, string) {
@@ -302,6 +659,37 @@ func reportSubdependencies(oldSubdependencies, newSubdependencies uint64) (strin
 	return fmtInt(int64(oldSubdependencies)), subdepsFmt, indicatorColor.Sprint(fmtInt(int64(difference)))
 }
 
+// specTypeLabel labels dependencies that aren't installed from a registry
+// version or range, e.g. git repositories or aliases.
+func specTypeLabel(specType string) string {
+	if specType == "" {
+		return ""
+	}
+
+	return " " + grayParens("%s", specType)
+}
+
+// deltaColor colors decreases green and increases red.
+func deltaColor(delta int64) *color.Color {
+	if delta < 0 {
+		return boldGreen
+	} else if delta > 0 {
+		return boldRed
+	}
+
+	return boldGray
+}
+
+func fmtSignedBytes(delta int64) string {
+	c := deltaColor(delta)
+
+	if delta < 0 {
+		return c.Sprintf("-%s", humanize.Bytes(uint64(-delta)))
+	}
+
+	return c.Sprintf("+%s", humanize.Bytes(uint64(delta)))
+}
+
 func grayParens(s string, args ...any) string {
 	a := gray.Sprint("(")
 	b := gray.Sprint(")")
@@ -317,36 +705,30 @@ func fmtInt(v int64) string {
 	return humanize.Comma(v)
 }
 
-func formattedTraffic(downloads *uint64, oldSize, newSize uint64) (string, string, string) {
+// formattedTraffic formats the traffic before and after the modification and
+// how much is saved or wasted.
+func formattedTraffic(t trafficChange) (string, string, string) {
 	indicatorColor := boldGray
 
-	if downloads == nil {
+	if t.Old == nil || t.New == nil {
 		return "N/A", indicatorColor.Sprint("N/A"), indicatorColor.Sprint("N/A")
 	}
 
-	if newSize > oldSize {
+	saved := t.Saved()
+	if saved.Sign() < 0 {
 		indicatorColor = boldRed
-	} else if newSize < oldSize {
+	} else if saved.Sign() > 0 {
 		indicatorColor = boldGreen
 	}
 
-	oldTrafficLastWeek := big.NewInt(int64(*downloads * oldSize))
-	oldTrafficLastWeekFmt := humanize.BigBytes(oldTrafficLastWeek)
-	estNewTraffic := big.NewInt(int64(*downloads * newSize))
-	estNewTrafficFmt := humanize.BigBytes(estNewTraffic)
-
-	estTrafficChange := big.NewInt(0).Sub(oldTrafficLastWeek, estNewTraffic)
 	estTrafficChangeFmt := ""
-	if estTrafficChange.Cmp(big.NewInt(0)) == 0 {
+	if saved.Sign() == 0 {
 		estTrafficChangeFmt = indicatorColor.Sprintf("No change")
-	} else if estTrafficChange.Cmp(big.NewInt(0)) > 0 {
-		estTrafficChangeFmt = "%s saved"
-		estTrafficChangeFmt = indicatorColor.Sprintf(estTrafficChangeFmt, humanize.BigBytes(estTrafficChange))
+	} else if saved.Sign() > 0 {
+		estTrafficChangeFmt = indicatorColor.Sprintf("%s saved", humanize.BigBytes(saved))
 	} else {
-		estTrafficChange.Mul(estTrafficChange, big.NewInt(-1))
-		estTrafficChangeFmt = "%s wasted"
-		estTrafficChangeFmt = indicatorColor.Sprintf(estTrafficChangeFmt, humanize.BigBytes(estTrafficChange))
+		estTrafficChangeFmt = indicatorColor.Sprintf("%s wasted", humanize.BigBytes(saved.Neg(saved)))
 	}
 
-	return oldTrafficLastWeekFmt, indicatorColor.Sprint(estNewTrafficFmt), estTrafficChangeFmt
+	return humanize.BigBytes(t.Old), indicatorColor.Sprint(humanize.BigBytes(t.New)), estTrafficChangeFmt
 }
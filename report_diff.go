@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"package_size_calculator/pkg/time_helpers"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/rs/zerolog/log"
+)
+
+// diffReports compares two JSON reports of the same analysis, e.g. to see how
+// sizes, downloads and traffic changed since the last run.
+func diffReports(oldPath, newPath string) {
+	oldReport, err := loadReport(oldPath)
+	if err != nil {
+		log.Fatal().Err(err).Str("path", oldPath).Msg("Failed to load old report")
+	}
+
+	newReport, err := loadReport(newPath)
+	if err != nil {
+		log.Fatal().Err(err).Str("path", newPath).Msg("Failed to load new report")
+	}
+
+	if reportSubject(oldReport.Report) != reportSubject(newReport.Report) {
+		log.Warn().
+			Str("old", reportSubject(oldReport.Report)).
+			Str("new", reportSubject(newReport.Report)).
+			Msg("Reports are of different packages")
+	}
+
+	err = writeTextReport(func(w io.Writer) { renderReportDiff(w, oldReport.Report, newReport.Report) })
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to write diff")
+	}
+}
+
+// diffEntry is a measured package in a report, identified by its section and
+// name.
+type diffEntry struct {
+	Title string
+	Stats calculatedStats
+}
+
+// statDiff is a stat in both reports. Old and New are nil if the stat is
+// unknown in the report.
+type statDiff struct {
+	Label  string
+	Old    *big.Int
+	New    *big.Int
+	Format func(*big.Int) string
+	// Neutral stats aren't colored as better or worse, e.g. downloads
+	Neutral bool
+}
+
+func renderReportDiff(w io.Writer, oldReport, newReport *Report) {
+	boldGreen.Fprintln(w, "Report diff")
+	boldGreen.Fprintln(w, "===========")
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "%s: %s\n", bold.Sprint("Old report"), formatReportTime(oldReport))
+	fmt.Fprintf(
+		w,
+		"%s: %s %s\n",
+		bold.Sprint("New report"),
+		formatReportTime(newReport),
+		grayParens("%s later", time_helpers.FormatDuration(newReport.GeneratedAt.Sub(oldReport.GeneratedAt))),
+	)
+
+	oldEntries := diffEntries(oldReport)
+	newEntries := diffEntries(newReport)
+
+	newByTitle := make(map[string]diffEntry, len(newEntries))
+	for _, e := range newEntries {
+		newByTitle[e.Title] = e
+	}
+	oldByTitle := make(map[string]diffEntry, len(oldEntries))
+	for _, e := range oldEntries {
+		oldByTitle[e.Title] = e
+	}
+
+	for _, o := range oldEntries {
+		n, ok := newByTitle[o.Title]
+		if !ok {
+			continue
+		}
+
+		fmt.Fprintln(w)
+		bold.Fprintf(w, "%s:\n", o.Title)
+		renderStatDiffs(w, calculatedStatDiffs(o.Stats, n.Stats))
+	}
+
+	for _, o := range oldEntries {
+		if _, ok := newByTitle[o.Title]; !ok {
+			fmt.Fprintln(w)
+			fmt.Fprintf(w, "%s: %s\n", bold.Sprint(o.Title), boldGray.Sprint("only in old report"))
+		}
+	}
+	for _, n := range newEntries {
+		if _, ok := oldByTitle[n.Title]; !ok {
+			fmt.Fprintln(w)
+			fmt.Fprintf(w, "%s: %s\n", bold.Sprint(n.Title), boldGray.Sprint("only in new report"))
+		}
+	}
+
+	o := oldReport.Statistics
+	n := newReport.Statistics
+
+	fmt.Fprintln(w)
+	bold.Fprintln(w, "Estimated new statistics:")
+	renderStatDiffs(w, []statDiff{
+		{Label: "Package size", Old: bigUint(o.NewSize), New: bigUint(n.NewSize), Format: humanize.BigBytes},
+		{Label: "Subdependencies", Old: bigUint(o.NewSubdependencies), New: bigUint(n.NewSubdependencies), Format: humanize.BigComma},
+		{Label: "Traffic for current version", Old: o.Traffic.New, New: n.Traffic.New, Format: humanize.BigBytes},
+		{Label: "Traffic for all versions", Old: o.TrafficAllVersions.New, New: n.TrafficAllVersions.New, Format: humanize.BigBytes},
+	})
+}
+
+// diffEntries lists the measured packages of a report. The packages are
+// matched by their name with the version or spec, so only the same versions
+// are compared.
+func diffEntries(r *Report) []diffEntry {
+	var entries []diffEntry
+
+	for _, p := range r.Packages {
+		entries = append(entries, diffEntry{Title: fmt.Sprintf("Package \"%s\"", p.Name), Stats: p.Stats})
+	}
+	for _, d := range r.Removed {
+		entries = append(entries, diffEntry{Title: fmt.Sprintf("Removed dependency \"%s\"", d.Name), Stats: d.Stats})
+	}
+	for _, d := range r.Added {
+		entries = append(entries, diffEntry{Title: fmt.Sprintf("Added dependency \"%s\"", d.Name), Stats: d.Stats})
+	}
+
+	return entries
+}
+
+func calculatedStatDiffs(o, n calculatedStats) []statDiff {
+	return []statDiff{
+		{Label: "Size", Old: bigUint(o.Size), New: bigUint(n.Size), Format: humanize.BigBytes},
+		{Label: "Subdependencies", Old: bigUint(o.Subdependencies), New: bigUint(n.Subdependencies), Format: humanize.BigComma},
+		{Label: "Downloads last week", Old: bigUintPtr(o.DownloadsLastWeek), New: bigUintPtr(n.DownloadsLastWeek), Format: humanize.BigComma, Neutral: true},
+		{Label: "Downloads of all versions", Old: bigUint(o.TotalDownloads), New: bigUint(n.TotalDownloads), Format: humanize.BigComma, Neutral: true},
+		{Label: "Traffic last week", Old: bigUintPtr(o.TrafficLastWeek), New: bigUintPtr(n.TrafficLastWeek), Format: humanize.BigBytes},
+	}
+}
+
+func renderStatDiffs(w io.Writer, diffs []statDiff) {
+	for _, d := range diffs {
+		if d.Old == nil || d.New == nil {
+			fmt.Fprintf(w, "  %s: %s %s %s\n", bold.Sprint(d.Label), formatStat(d.Old, d.Format), arrow, formatStat(d.New, d.Format))
+			continue
+		}
+
+		delta := new(big.Int).Sub(d.New, d.Old)
+
+		c := deltaColor(int64(delta.Sign()))
+		if d.Neutral {
+			c = bold
+		}
+
+		sign := "+"
+		if delta.Sign() < 0 {
+			sign = "-"
+		}
+
+		fmt.Fprintf(
+			w,
+			"  %s: %s %s %s %s\n",
+			bold.Sprint(d.Label),
+			d.Format(d.Old),
+			arrow,
+			c.Sprint(d.Format(d.New)),
+			grayParens("%s", c.Sprint(sign+d.Format(new(big.Int).Abs(delta)))),
+		)
+	}
+}
+
+func formatStat(v *big.Int, format func(*big.Int) string) string {
+	if v == nil {
+		return "N/A"
+	}
+
+	return format(v)
+}
+
+func formatReportTime(r *Report) string {
+	return fmt.Sprintf("%s %s", r.GeneratedAt.Format(time.DateTime), grayParens("%s", reportSubject(r)))
+}
+
+func bigUint(v uint64) *big.Int {
+	return new(big.Int).SetUint64(v)
+}
+
+func bigUintPtr(v *uint64) *big.Int {
+	if v == nil {
+		return nil
+	}
+
+	return bigUint(*v)
+}
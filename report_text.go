@@ -0,0 +1,471 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"package_size_calculator/pkg/time_helpers"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/fatih/color"
+)
+
+// longTextRenderer shows every detail of the report in the terminal.
+type longTextRenderer struct{}
+
+func (longTextRenderer) Render(out io.Writer, r *Report) error {
+	w := &errWriter{w: out}
+
+	renderTextHeader(w, r)
+
+	for _, p := range r.Packages {
+		fmt.Fprintln(w)
+		renderLongPackageInfo(w, r, p)
+	}
+
+	if len(r.Removed) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, color.RedString("Removed dependencies:"))
+
+		parent := r.Packages[0]
+		parentName := boldYellow.Sprint(parent.Name)
+
+		for _, d := range r.Removed {
+			pcTrafficOfPackageFmt := "N/A"
+			if d.PercentOfTraffic != nil {
+				pcTrafficOfPackageFmt = fmtPercent(*d.PercentOfTraffic)
+			}
+
+			exclusiveFmt := "N/A"
+			pcExclusiveFmt := "N/A"
+			exclusivePackagesFmt := "N/A"
+			if d.Exclusive != nil {
+				exclusiveFmt = humanize.Bytes(d.Exclusive.Size)
+				pcExclusiveFmt = fmtPercent(d.Exclusive.PercentOfSize)
+				exclusivePackagesFmt = fmtInt(int64(d.Exclusive.Packages))
+			}
+
+			fmt.Fprintf(
+				w,
+				"  %s %s%s: %s %s\n",
+				color.RedString("-"),
+				boldYellow.Sprint(d.Name),
+				specTypeLabel(d.SpecType),
+				humanize.Bytes(d.Stats.Size),
+				grayParens("%s%%", fmtPercent(d.PercentOfSize)),
+			)
+			fmt.Fprintf(
+				w,
+				"    %s: %s %s\n",
+				bold.Sprint("Exclusive size"),
+				exclusiveFmt,
+				grayParens("%s%%, %s packages", pcExclusiveFmt, exclusivePackagesFmt),
+			)
+			fmt.Fprintf(
+				w,
+				"    %s: %s %s\n",
+				bold.Sprint("Downloads last week"),
+				d.Stats.FormattedDownloadsLastWeek(),
+				grayParens("%s%% from %s", d.Stats.FormattedPercentDownloadsOfVersion(), boldYellow.Sprint(d.Version)),
+			)
+			fmt.Fprintf(
+				w,
+				"    %s: %s %s\n",
+				bold.Sprintf("Downloads last week from \"%s\"", parentName),
+				parent.Stats.FormattedDownloadsLastWeek(),
+				grayParens("%s%%", pcTrafficOfPackageFmt),
+			)
+			fmt.Fprintf(w, "    %s: %s\n", bold.Sprint("Traffic last week"), d.Stats.FormattedTrafficLastWeek())
+			fmt.Fprintf(
+				w,
+				"    %s: %s %s\n",
+				bold.Sprintf("Traffic from \"%s\"", parentName),
+				parent.Stats.FormattedTrafficLastWeek(),
+				grayParens("%s%%", pcTrafficOfPackageFmt),
+			)
+			fmt.Fprintf(
+				w,
+				"    %s: %s %s\n",
+				bold.Sprint("Subdependencies"),
+				d.Stats.FormattedSubdependencies(),
+				grayParens("%s%%", fmtPercent(d.PercentOfSubdependencies)),
+			)
+			reportDeprecated(w, d.Deprecated, "    ")
+		}
+	}
+
+	if r.ExclusiveOfRemoved != nil {
+		fmt.Fprintf(
+			w,
+			"  %s: %s %s\n",
+			bold.Sprint("Exclusive size of all removed dependencies"),
+			humanize.Bytes(r.ExclusiveOfRemoved.Size),
+			grayParens(
+				"%s%%, %s packages",
+				fmtPercent(r.ExclusiveOfRemoved.PercentOfSize),
+				fmtInt(int64(r.ExclusiveOfRemoved.Packages)),
+			),
+		)
+	}
+
+	if len(r.Added) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, color.GreenString("Added dependencies:"))
+
+		for _, d := range r.Added {
+			fmt.Fprintf(
+				w,
+				"  %s %s: %s %s\n",
+				color.GreenString("+"),
+				boldYellow.Sprint(d.Name),
+				humanize.Bytes(d.Stats.Size),
+				grayParens("%s%%", fmtPercent(d.PercentOfSize)),
+			)
+			fmt.Fprintf(
+				w,
+				"    %s: %s %s\n",
+				bold.Sprint("Downloads last week"),
+				d.Stats.FormattedDownloadsLastWeek(),
+				grayParens("%s%% from %s", d.Stats.FormattedPercentDownloadsOfVersion(), boldYellow.Sprint(d.Version)),
+			)
+			fmt.Fprintf(w, "    %s: %s\n", bold.Sprint("Estimated traffic last week"), d.Stats.FormattedTrafficLastWeek())
+			fmt.Fprintf(
+				w,
+				"    %s: %s %s\n",
+				bold.Sprint("Subdependencies"),
+				d.Stats.FormattedSubdependencies(),
+				grayParens("%s%%", fmtPercent(d.PercentOfSubdependencies)),
+			)
+		}
+	}
+
+	if len(r.Overrides) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, color.YellowString("Overrides:"))
+
+		for _, o := range r.Overrides {
+			fmt.Fprintf(
+				w,
+				"  %s %s: %s %s\n",
+				color.YellowString("~"),
+				boldYellow.Sprint(o.Name),
+				fmtSignedBytes(o.SizeDelta),
+				grayParens("%s%%", fmtPercent(o.PercentSizeDelta)),
+			)
+			fmt.Fprintf(
+				w,
+				"    %s: %s %s\n",
+				bold.Sprint("Subdependencies"),
+				deltaColor(o.SubdependenciesDelta).Sprintf("%+d", o.SubdependenciesDelta),
+				grayParens("%s%%", fmtPercent(o.PercentSubdependenciesDelta)),
+			)
+		}
+	}
+
+	fmt.Fprintln(w)
+	renderLongStatistics(w, r.Statistics, r.EgressPrice)
+	renderKindChanges(w, r.Kinds)
+
+	for _, c := range r.Counts {
+		oldFmt, newFmt, _ := reportSubdependencies(c.Old, c.New)
+		fmt.Fprintf(w, "  %s: %s %s %s\n", bold.Sprint(c.Label), oldFmt, arrow, newFmt)
+	}
+
+	reportLicenseChanges(w, r.Licenses)
+	reportVersionMix(w, r.Statistics.VersionMix)
+	renderLongEnergy(w, r.Energy)
+
+	return w.err
+}
+
+func renderLongPackageInfo(w io.Writer, r *Report, p packageSummary) {
+	fmt.Fprintf(w, "%s: %s\n", bold.Sprintf("Package info for \"%s\"", boldYellow.Sprint(p.Name)), humanize.Bytes(p.Stats.Size))
+	fmt.Fprintf(
+		w,
+		"  %s: %s %s\n",
+		bold.Sprint("Released"),
+		p.ReleaseTime,
+		grayParens("%s ago", time_helpers.FormatDuration(r.GeneratedAt.Sub(p.ReleaseTime))),
+	)
+	fmt.Fprintf(
+		w,
+		"  %s: %s %s\n",
+		bold.Sprint("Downloads last week"),
+		p.Stats.FormattedDownloadsLastWeek(),
+		grayParens("%s%%", p.Stats.FormattedPercentDownloadsOfVersion()),
+	)
+	fmt.Fprintf(w, "  %s: %s\n", bold.Sprint("Estimated traffic last week"), p.Stats.FormattedTrafficLastWeek())
+	if p.Estimate != nil {
+		reportEstimateAccuracy(w, *p.Estimate)
+	}
+	fmt.Fprintf(w, "  %s: %s\n", bold.Sprint("Subdependencies"), p.Stats.FormattedSubdependencies())
+	for _, k := range p.Kinds {
+		fmt.Fprintf(
+			w,
+			"    %s: %s %s\n",
+			bold.Sprintf("%s", kindLabel(k.Kind)),
+			fmtInt(int64(k.Packages)),
+			grayParens("%s", humanize.Bytes(k.Size)),
+		)
+	}
+
+	reportInstallScripts(w, p)
+	reportDeprecated(w, p.Deprecated, "  ")
+	reportLicenses(w, p.Licenses)
+
+	if p.LatestVersion != nil {
+		fmt.Fprintf(
+			w,
+			"  %s: %s %s\n",
+			bold.Sprint("Latest version"),
+			p.LatestVersion.Version,
+			grayParens("%s ago", time_helpers.FormatDuration(r.GeneratedAt.Sub(p.LatestVersion.ReleaseTime))),
+		)
+	}
+}
+
+func renderLongStatistics(w io.Writer, s statisticsChange, price *egressPrice) {
+	indicatorColor := deltaColor(int64(s.NewSize) - int64(s.OldSize))
+	pcSizeFmt := indicatorColor.Sprintf("%s%%", fmtPercent(s.PercentSize))
+
+	oldTrafficLastWeekFmt, estNewTrafficFmt, estTrafficChangeFmt := formattedTraffic(s.Traffic)
+	scaledOldTrafficLastWeekFmt, scaledEstTrafficNextWeekFmt, scaledEstTrafficChangeFmt := formattedTraffic(s.TrafficAllVersions)
+	oldSubdepsFmt, estSubdepsFmt, subdepsChangeFmt := reportSubdependencies(s.OldSubdependencies, s.NewSubdependencies)
+
+	bold.Fprintln(w, "Estimated new statistics:")
+	fmt.Fprintf(
+		w,
+		"  %s: %s %s %s %s\n",
+		bold.Sprint("Package size"),
+		humanize.Bytes(s.OldSize),
+		arrow,
+		indicatorColor.Sprint(humanize.Bytes(s.NewSize)),
+		grayParens("%s", pcSizeFmt),
+	)
+	fmt.Fprintf(
+		w,
+		"  %s: %s %s %s %s\n",
+		bold.Sprint("Subdependencies"),
+		oldSubdepsFmt,
+		arrow,
+		estSubdepsFmt,
+		grayParens("%s", subdepsChangeFmt),
+	)
+	bold.Fprintln(w, "  Traffic with last week's downloads:")
+	fmt.Fprintf(
+		w,
+		"    %s: %s %s %s %s\n",
+		bold.Sprint("For current version"),
+		oldTrafficLastWeekFmt,
+		arrow,
+		estNewTrafficFmt,
+		grayParens("%s", estTrafficChangeFmt),
+	)
+	renderProjections(w, s.Traffic, price)
+
+	// Only the measured version changes in a mix, so its savings would be the
+	// same as those of the current version
+	if s.VersionMix != nil {
+		if s.TrafficAllVersions.Old != nil {
+			fmt.Fprintf(
+				w,
+				"    %s: %s %s %s\n",
+				bold.Sprint("For version mix"),
+				humanize.BigBytes(s.TrafficAllVersions.Old),
+				arrow,
+				humanize.BigBytes(s.TrafficAllVersions.New),
+			)
+		}
+
+		return
+	}
+
+	fmt.Fprintf(
+		w,
+		"    %s: %s %s %s %s\n",
+		bold.Sprint("For all versions"),
+		scaledOldTrafficLastWeekFmt,
+		arrow,
+		indicatorColor.Sprint(scaledEstTrafficNextWeekFmt),
+		grayParens("%s", scaledEstTrafficChangeFmt),
+	)
+	renderProjections(w, s.TrafficAllVersions, price)
+}
+
+// renderProjections shows the saved traffic and its cost over longer periods
+// below a traffic line.
+func renderProjections(w io.Writer, t trafficChange, price *egressPrice) {
+	volume, cost := formattedProjections(t.Projections)
+	if volume == "" {
+		return
+	}
+
+	c := deltaColor(int64(-t.Saved().Sign()))
+	fmt.Fprintf(w, "      %s: %s\n", bold.Sprint("Projected"), c.Sprint(volume))
+	if cost != "" {
+		fmt.Fprintf(w, "      %s: %s %s\n", bold.Sprint("Egress cost"), c.Sprint(cost), grayParens("%s", price))
+	}
+}
+
+// renderLongEnergy shows the energy and emissions the modification saves in a
+// year, if they were requested.
+func renderLongEnergy(w io.Writer, e *energyChange) {
+	if e == nil {
+		return
+	}
+
+	fmt.Fprintln(w)
+	bold.Fprintln(w, "Estimated energy and emissions per year:")
+
+	for _, line := range e.rows() {
+		if line.Estimate == nil {
+			fmt.Fprintf(w, "  %s: %s\n", bold.Sprint(line.Label), "N/A")
+			continue
+		}
+
+		c := deltaColor(-energySign(line.Estimate))
+		fmt.Fprintf(w, "  %s: %s %s\n", bold.Sprint(line.Label), c.Sprint(formattedEnergy(line.Estimate)), grayParens("%s", formattedEnergyBreakdown(line.Estimate)))
+	}
+
+	fmt.Fprintf(w, "  %s\n", gray.Sprintf("Model: %s", e.Model))
+}
+
+// renderKindChanges shows how the installed dependencies of each kind
+// change.
+func renderKindChanges(w io.Writer, changes []kindChange) {
+	if len(changes) == 0 {
+		return
+	}
+
+	bold.Fprintln(w, "  By dependency kind:")
+	for _, c := range changes {
+		oldSubdepsFmt, newSubdepsFmt, _ := reportSubdependencies(c.Old.Packages, c.New.Packages)
+		sizeIndicatorColor := deltaColor(int64(c.New.Size) - int64(c.Old.Size))
+
+		fmt.Fprintf(
+			w,
+			"    %s: %s %s %s %s\n",
+			bold.Sprintf("%s", kindLabel(c.Kind)),
+			oldSubdepsFmt,
+			arrow,
+			newSubdepsFmt,
+			grayParens("%s %s %s", humanize.Bytes(c.Old.Size), arrow, sizeIndicatorColor.Sprint(humanize.Bytes(c.New.Size))),
+		)
+	}
+}
+
+// shortTextRenderer shows a shorter version of the report, ideal for posts to
+// Twitter.
+type shortTextRenderer struct{}
+
+func (shortTextRenderer) Render(out io.Writer, r *Report) error {
+	w := &errWriter{w: out}
+
+	renderTextHeader(w, r)
+
+	for _, p := range r.Packages {
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "%s: %s\n", boldYellow.Sprint(p.Name), humanize.Bytes(p.Stats.Size))
+		fmt.Fprintf(w, "  %s: %s ago\n", bold.Sprint("Released"), time_helpers.FormatDuration(r.GeneratedAt.Sub(p.ReleaseTime)))
+		fmt.Fprintf(w, "  %s: %s\n", bold.Sprint("DLs last week"), p.Stats.FormattedPercentDownloadsOfVersion())
+	}
+
+	if len(r.Removed) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, color.RedString("Removed deps:"))
+
+		for _, d := range r.Removed {
+			exclusiveFmt := "N/A"
+			if d.Exclusive != nil {
+				exclusiveFmt = humanize.Bytes(d.Exclusive.Size)
+			}
+
+			fmt.Fprintf(
+				w,
+				"  %s %s%s: %s %s\n",
+				color.RedString("-"),
+				boldYellow.Sprint(d.Name),
+				specTypeLabel(d.SpecType),
+				humanize.Bytes(d.Stats.Size),
+				grayParens("%s exclusive", exclusiveFmt),
+			)
+			fmt.Fprintf(
+				w,
+				"    %s: %s %s\n",
+				bold.Sprint("DLs last week"),
+				d.Stats.FormattedDownloadsLastWeek(),
+				grayParens("%s", d.Stats.FormattedTrafficLastWeek()),
+			)
+		}
+	}
+
+	if len(r.Added) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, color.GreenString("Added dependencies:"))
+
+		for _, d := range r.Added {
+			fmt.Fprintf(w, "  %s %s: %s\n", color.GreenString("+"), boldYellow.Sprint(d.Name), humanize.Bytes(d.Stats.Size))
+			fmt.Fprintf(w, "    %s: %s\n", bold.Sprint("DLs last week"), d.Stats.FormattedDownloadsLastWeek())
+		}
+	}
+
+	if len(r.Overrides) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, color.YellowString("Overrides:"))
+
+		for _, o := range r.Overrides {
+			fmt.Fprintf(w, "  %s %s: %s\n", color.YellowString("~"), boldYellow.Sprint(o.Name), fmtSignedBytes(o.SizeDelta))
+		}
+	}
+
+	s := r.Statistics
+	indicatorColor := deltaColor(int64(s.NewSize) - int64(s.OldSize))
+	oldTrafficLastWeekFmt, estNewTrafficFmt, estTrafficChangeFmt := formattedTraffic(s.Traffic)
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(
+		w,
+		"%s: %s %s %s %s\n",
+		bold.Sprint("Est. size"),
+		humanize.Bytes(s.OldSize),
+		arrow,
+		indicatorColor.Sprint(humanize.Bytes(s.NewSize)),
+		grayParens("%s", indicatorColor.Sprintf("%s%%", fmtPercent(s.PercentSize))),
+	)
+	fmt.Fprintf(
+		w,
+		"%s: %s %s %s %s\n",
+		bold.Sprint("Est. traffic"),
+		oldTrafficLastWeekFmt,
+		arrow,
+		indicatorColor.Sprint(estNewTrafficFmt),
+		grayParens("%s", estTrafficChangeFmt),
+	)
+
+	// Only the yearly projection of all versions fits into a short post
+	if yearly, ok := yearlyProjection(s.AllVersionsSavings()); ok {
+		c := deltaColor(int64(-yearly.Saved.Sign()))
+		line := fmt.Sprintf("%s %s", fmtBigBytesAbs(yearly.Saved), savedLabel(yearly.Saved.Sign(), "saved", "wasted"))
+		if yearly.Cost != nil {
+			line += " " + grayParens("%s", fmtMoney(abs(*yearly.Cost)))
+		}
+
+		fmt.Fprintf(w, "%s: %s\n", bold.Sprint("Est. traffic/year"), c.Sprint(line))
+	}
+
+	if e := r.Energy.Headline(); e != nil {
+		fmt.Fprintf(w, "%s: %s\n", bold.Sprint("Est. energy/year"), deltaColor(-energySign(e)).Sprint(formattedEnergy(e)))
+	}
+
+	return w.err
+}
+
+func renderTextHeader(w io.Writer, r *Report) {
+	if r.Title == "" {
+		return
+	}
+
+	fmt.Fprintln(w)
+	boldGreen.Fprintln(w, r.Title)
+	boldGreen.Fprintln(w, strings.Repeat("=", len(r.Title)))
+}
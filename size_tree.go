@@ -0,0 +1,111 @@
+package main
+
+import (
+	"cmp"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// minSizeTreeShare is the share of the total size below which directories are
+// merged, so that reports of big trees stay small
+const minSizeTreeShare = 0.001
+
+// sizeTree is the size of a directory and its subdirectories, as shown in the
+// treemap of the HTML report.
+type sizeTree struct {
+	Name string `json:"name"`
+	Size uint64 `json:"size"`
+	// Package is set for the directories of installed packages
+	Package  bool        `json:"package,omitempty"`
+	Children []*sizeTree `json:"children,omitempty"`
+}
+
+// buildSizeTree measures the directory like internal.DirSize and records the
+// sizes of its subdirectories. Files are combined into one entry per
+// directory and small directories are merged.
+func buildSizeTree(dir string) (*sizeTree, error) {
+	t, err := walkSizeTree(dir, filepath.Base(dir))
+	if err != nil {
+		return nil, err
+	}
+
+	t.prune(uint64(float64(t.Size) * minSizeTreeShare))
+
+	return t, nil
+}
+
+func walkSizeTree(dir, name string) (*sizeTree, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &sizeTree{Name: name}
+	files := &sizeTree{Name: "(files)"}
+
+	for _, e := range entries {
+		// Symlinks, e.g. in node_modules/.bin, aren't followed
+		if e.Type()&os.ModeSymlink != 0 {
+			continue
+		}
+
+		if e.IsDir() {
+			child, err := walkSizeTree(filepath.Join(dir, e.Name()), e.Name())
+			if err != nil {
+				return nil, err
+			}
+
+			t.Size += child.Size
+			t.Children = append(t.Children, child)
+
+			continue
+		}
+
+		if e.Name() == "package.json" {
+			t.Package = true
+		}
+
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		files.Size += uint64(info.Size())
+	}
+
+	t.Size += files.Size
+
+	// Directories that only contain files are shown as one entry
+	if len(t.Children) > 0 && files.Size > 0 {
+		t.Children = append(t.Children, files)
+	}
+
+	slices.SortFunc(t.Children, func(a, b *sizeTree) int {
+		return cmp.Compare(b.Size, a.Size)
+	})
+
+	return t, nil
+}
+
+// prune merges the children smaller than minSize into one entry.
+func (t *sizeTree) prune(minSize uint64) {
+	var kept []*sizeTree
+	other := &sizeTree{Name: "(other)"}
+
+	for _, c := range t.Children {
+		if c.Size < minSize {
+			other.Size += c.Size
+			continue
+		}
+
+		c.prune(minSize)
+		kept = append(kept, c)
+	}
+
+	if other.Size > 0 && len(kept) > 0 {
+		kept = append(kept, other)
+	}
+
+	t.Children = kept
+}
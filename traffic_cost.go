@@ -0,0 +1,187 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+)
+
+var ErrInvalidEgressPrice = errors.New("invalid egress price")
+
+// egressPricePresets are the list prices in USD per GB of internet egress in
+// the first pricing tier. Bigger volumes are cheaper with every provider, so
+// the costs are an upper bound.
+var egressPricePresets = map[string]float64{
+	"aws":        0.09,
+	"azure":      0.087,
+	"gcp":        0.12,
+	"cloudflare": 0,
+}
+
+// egressPrice is the price traffic is billed at.
+type egressPrice struct {
+	// Name is the preset, empty for custom prices
+	Name  string
+	PerGB float64
+}
+
+func (p egressPrice) String() string {
+	price := fmt.Sprintf("%s/GB", fmtMoney(p.PerGB))
+	if p.Name == "" {
+		return price
+	}
+
+	return fmt.Sprintf("%s, %s", p.Name, price)
+}
+
+// parseEgressPrice parses a preset name or a price in USD per GB. An empty
+// price disables cost estimates.
+func parseEgressPrice(s string) (*egressPrice, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+
+	if perGB, ok := egressPricePresets[strings.ToLower(s)]; ok {
+		return &egressPrice{Name: strings.ToLower(s), PerGB: perGB}, nil
+	}
+
+	perGB, err := strconv.ParseFloat(strings.TrimPrefix(s, "$"), 64)
+	if err != nil || perGB < 0 {
+		presets := make([]string, 0, len(egressPricePresets))
+		for name := range egressPricePresets {
+			presets = append(presets, name)
+		}
+		slices.Sort(presets)
+
+		return nil, fmt.Errorf("%w: \"%s\" is neither a price per GB nor one of %s", ErrInvalidEgressPrice, s, strings.Join(presets, ", "))
+	}
+
+	return &egressPrice{PerGB: perGB}, nil
+}
+
+// Cost returns the price of the traffic in USD.
+func (p egressPrice) Cost(bytes *big.Int) float64 {
+	return gigabytes(bytes) * p.PerGB
+}
+
+func gigabytes(bytes *big.Int) float64 {
+	gb, _ := new(big.Float).Quo(new(big.Float).SetInt(bytes), big.NewFloat(1e9)).Float64()
+
+	return gb
+}
+
+type trafficPeriod struct {
+	Name  string
+	Weeks float64
+}
+
+// trafficPeriods are the periods traffic is projected over, assuming that
+// every week has as many downloads as last week
+var trafficPeriods = []trafficPeriod{
+	{Name: "month", Weeks: 365.0 / 12 / 7},
+	{Name: "quarter", Weeks: 365.0 / 4 / 7},
+	{Name: "year", Weeks: 365.0 / 7},
+}
+
+// trafficProjection is the traffic a modification saves over a period.
+type trafficProjection struct {
+	Period string
+	// Saved is negative if the modification causes more traffic
+	Saved *big.Int
+	// Cost is the price of the saved traffic in USD, only set if an egress
+	// price is configured
+	Cost *float64
+}
+
+// projectTraffic projects the traffic saved in a week over every period.
+func projectTraffic(savedPerWeek *big.Int, price *egressPrice) []trafficProjection {
+	projections := make([]trafficProjection, 0, len(trafficPeriods))
+	for _, period := range trafficPeriods {
+		saved, _ := new(big.Float).Mul(new(big.Float).SetInt(savedPerWeek), big.NewFloat(period.Weeks)).Int(nil)
+
+		p := trafficProjection{Period: period.Name, Saved: saved}
+		if price != nil {
+			cost := price.Cost(saved)
+			p.Cost = &cost
+		}
+
+		projections = append(projections, p)
+	}
+
+	return projections
+}
+
+// fmtMoney formats an amount of USD with cents.
+func fmtMoney(v float64) string {
+	if v < 0 {
+		return "-$" + humanize.FormatFloat("#,###.##", -v)
+	}
+
+	return "$" + humanize.FormatFloat("#,###.##", v)
+}
+
+// fmtBigBytesAbs formats the absolute size of a delta, whose sign is shown
+// separately with "saved" or "wasted".
+func fmtBigBytesAbs(v *big.Int) string {
+	return humanize.BigBytes(new(big.Int).Abs(v))
+}
+
+// formattedProjections formats the projected traffic and, if an egress price
+// is configured, its cost. The cost is empty otherwise.
+func formattedProjections(projections []trafficProjection) (string, string) {
+	if len(projections) == 0 {
+		return "", ""
+	}
+
+	volumes := make([]string, 0, len(projections))
+	costs := make([]string, 0, len(projections))
+	for _, p := range projections {
+		volumes = append(volumes, fmt.Sprintf("%s/%s", fmtBigBytesAbs(p.Saved), p.Period))
+		if p.Cost != nil {
+			costs = append(costs, fmt.Sprintf("%s/%s", fmtMoney(abs(*p.Cost)), p.Period))
+		}
+	}
+
+	sign := projections[0].Saved.Sign()
+	volume := strings.Join(volumes, ", ") + " " + savedLabel(sign, "saved", "wasted")
+
+	cost := ""
+	if len(costs) > 0 {
+		cost = strings.Join(costs, ", ") + " " + savedLabel(sign, "saved", "extra")
+	}
+
+	return volume, cost
+}
+
+// yearlyProjection returns the projection of the traffic over a year.
+func yearlyProjection(t trafficChange) (trafficProjection, bool) {
+	for _, p := range t.Projections {
+		if p.Period == "year" {
+			return p, true
+		}
+	}
+
+	return trafficProjection{}, false
+}
+
+func savedLabel(sign int, saved, wasted string) string {
+	if sign < 0 {
+		return wasted
+	}
+
+	return saved
+}
+
+func abs(v float64) float64 {
+	if v < 0 {
+		return -v
+	}
+
+	return v
+}
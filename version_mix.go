@@ -0,0 +1,164 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"math/big"
+	"package_size_calculator/pkg/npm"
+	"slices"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/dustin/go-humanize"
+	"github.com/rs/zerolog/log"
+)
+
+// versionMix is the traffic of last week's downloads, with the sizes of the
+// most downloaded versions instead of the size of a single version.
+type versionMix struct {
+	Versions []versionShare
+	// OtherDownloads are the downloads of all other versions, which are
+	// assumed to have the average size of the most downloaded versions
+	OtherDownloads uint64
+	OtherSize      uint64
+	TotalDownloads uint64
+	Traffic        *big.Int
+}
+
+type versionShare struct {
+	Version   string
+	Downloads uint64
+	// Share is the percentage of all downloads
+	Share float64
+	Size  uint64
+	// Estimated is true if the size is estimated from the registry instead of
+	// measured
+	Estimated bool
+	Traffic   *big.Int
+}
+
+// measureVersionMix weighs last week's downloads with the sizes of the n most
+// downloaded versions. Measured versions keep their measured size, the others
+// are estimated from the registry because installing them all would take too
+// long.
+func measureVersionMix(info *npm.PackageInfo, downloads npm.Downloads, measured map[string]uint64, n int) *versionMix {
+	mix := &versionMix{TotalDownloads: downloads.Total()}
+
+	for version, count := range downloads {
+		if _, ok := info.Versions[version]; !ok || count == 0 {
+			continue
+		}
+
+		mix.Versions = append(mix.Versions, versionShare{
+			Version:   version,
+			Downloads: count,
+			Share:     calculatePercentage(float64(count), float64(mix.TotalDownloads)),
+		})
+	}
+
+	slices.SortFunc(mix.Versions, func(a, b versionShare) int {
+		if c := cmp.Compare(b.Downloads, a.Downloads); c != 0 {
+			return c
+		}
+
+		return cmp.Compare(a.Version, b.Version)
+	})
+	if len(mix.Versions) > n {
+		mix.Versions = mix.Versions[:n]
+	}
+
+	wg := sync.WaitGroup{}
+	for i := range mix.Versions {
+		v := &mix.Versions[i]
+		if size, ok := measured[v.Version]; ok {
+			v.Size = size
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			v.Size = estimatePackageSize(info.Versions[v.Version].JSON).Size
+			v.Estimated = true
+		}()
+	}
+	wg.Wait()
+
+	mix.Traffic = new(big.Int)
+	topDownloads := new(big.Int)
+	for i := range mix.Versions {
+		v := &mix.Versions[i]
+		v.Traffic = new(big.Int).Mul(new(big.Int).SetUint64(v.Downloads), new(big.Int).SetUint64(v.Size))
+
+		mix.Traffic.Add(mix.Traffic, v.Traffic)
+		topDownloads.Add(topDownloads, new(big.Int).SetUint64(v.Downloads))
+	}
+
+	mix.OtherDownloads = mix.TotalDownloads - topDownloads.Uint64()
+	if topDownloads.Sign() > 0 {
+		mix.OtherSize = new(big.Int).Quo(mix.Traffic, topDownloads).Uint64()
+	}
+	mix.Traffic.Add(mix.Traffic, new(big.Int).Mul(new(big.Int).SetUint64(mix.OtherDownloads), new(big.Int).SetUint64(mix.OtherSize)))
+
+	log.Info().
+		Str("package", info.Name).
+		Int("versions", len(mix.Versions)).
+		Str("traffic", humanize.BigBytes(mix.Traffic)).
+		Msg("Weighed traffic with the most downloaded versions")
+
+	return mix
+}
+
+// newMixedTrafficChange applies the size change of the modification to the
+// downloads of the measured version, the traffic of the other versions stays
+// the same. Nothing changes if the downloads of the version are unknown. The
+// savings are those of the current version, so there are no projections.
+func newMixedTrafficChange(mix *versionMix, downloads *uint64, oldSize, newSize uint64) trafficChange {
+	t := trafficChange{
+		Old: new(big.Int).Set(mix.Traffic),
+		New: new(big.Int).Set(mix.Traffic),
+	}
+
+	if downloads != nil {
+		delta := new(big.Int).Sub(new(big.Int).SetUint64(newSize), new(big.Int).SetUint64(oldSize))
+		t.New.Add(t.New, delta.Mul(delta, new(big.Int).SetUint64(*downloads)))
+	}
+
+	return t
+}
+
+// reportVersionMix shows the downloads, sizes and traffic of the most
+// downloaded versions.
+func reportVersionMix(w io.Writer, mix *versionMix) {
+	if mix == nil {
+		return
+	}
+
+	fmt.Fprintln(w)
+	bold.Fprintln(w, "Version mix of last week's downloads:")
+
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, "  Version\tShare\tSize\tTraffic")
+	for _, v := range mix.Versions {
+		note := ""
+		if v.Estimated {
+			note = " " + grayParens("estimated")
+		}
+
+		fmt.Fprintf(tw, "  %s\t%s%%\t%s\t%s%s\n", v.Version, fmtPercent(v.Share), humanize.Bytes(v.Size), humanize.BigBytes(v.Traffic), note)
+	}
+	if mix.OtherDownloads > 0 {
+		fmt.Fprintf(
+			tw,
+			"  %s\t%s%%\t%s\t%s %s\n",
+			"Other",
+			fmtPercent(calculatePercentage(float64(mix.OtherDownloads), float64(mix.TotalDownloads))),
+			humanize.Bytes(mix.OtherSize),
+			humanize.BigBytes(new(big.Int).Mul(new(big.Int).SetUint64(mix.OtherDownloads), new(big.Int).SetUint64(mix.OtherSize))),
+			grayParens("average size"),
+		)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestStatisticsChangeWithVersionMix(t *testing.T) {
+	// 100 downloads of the measured version and 300 of an older version of
+	// 2000 bytes
+	mix := &versionMix{TotalDownloads: 400, Traffic: big.NewInt(100*1000 + 300*2000)}
+	downloads := uint64(100)
+	s := newStatisticsChange(1000, 600, &downloads, 400, 0, 0, mix)
+
+	if s.TrafficAllVersions.Old.Int64() != 700000 || s.TrafficAllVersions.New.Int64() != 660000 {
+		t.Errorf("mixed traffic = %s → %s, want 700000 → 660000", s.TrafficAllVersions.Old, s.TrafficAllVersions.New)
+	}
+	if len(s.TrafficAllVersions.Projections) != 0 {
+		t.Errorf("mixed traffic has %d projections, want none", len(s.TrafficAllVersions.Projections))
+	}
+
+	// Only the measured version changes, so all versions save as much as it
+	savings := s.AllVersionsSavings()
+	if savings.Saved().Cmp(s.Traffic.Saved()) != 0 {
+		t.Errorf("savings of all versions = %s, want %s", savings.Saved(), s.Traffic.Saved())
+	}
+
+	e := newEnergyChange(s, &defaultEnergyModel)
+	if e.AllVersions != nil {
+		t.Error("energy of all versions is estimated with a version mix")
+	}
+	if e.Headline() != e.Current {
+		t.Error("energy headline isn't the current version's with a version mix")
+	}
+	if rows := e.rows(); len(rows) != 1 {
+		t.Errorf("energy has %d rows, want 1", len(rows))
+	}
+
+	var w bytes.Buffer
+	renderLongStatistics(&w, s, nil)
+	out := w.String()
+	if !strings.Contains(out, "For version mix") {
+		t.Errorf("version mix traffic is missing:\n%s", out)
+	}
+	if strings.Contains(out, "For all versions") {
+		t.Errorf("all versions traffic is shown with a version mix:\n%s", out)
+	}
+}
+
+func TestStatisticsChangeWithoutVersionMix(t *testing.T) {
+	downloads := uint64(100)
+	s := newStatisticsChange(1000, 600, &downloads, 400, 0, 0, nil)
+
+	if s.AllVersionsSavings().Saved().Int64() != 400*400 {
+		t.Errorf("savings of all versions = %s, want %d", s.AllVersionsSavings().Saved(), 400*400)
+	}
+
+	e := newEnergyChange(s, &defaultEnergyModel)
+	if e.AllVersions == nil || e.Headline() != e.AllVersions {
+		t.Error("energy headline isn't the estimate of all versions")
+	}
+	if rows := e.rows(); len(rows) != 2 {
+		t.Errorf("energy has %d rows, want 2", len(rows))
+	}
+}
@@ -1,59 +1,39 @@
 package main
 
 import (
-	"fmt"
-	"package_size_calculator/internal"
 	"package_size_calculator/pkg/npm"
 	"sync"
 
 	"github.com/dustin/go-humanize"
-	"github.com/manifoldco/promptui"
 	"github.com/rs/zerolog/log"
 )
 
 func calculateVersionSizeChange() {
 	pkg := promptPackageVersions(npmClient)
 
-	fmt.Println()
-	reportPackageInfo(&pkg.Old, false, 0)
-	fmt.Println()
-	reportPackageInfo(&pkg.New, false, 0)
-	fmt.Println()
-	reportEstimatedStatistics(
-		pkg.Old.Stats.Size,
-		pkg.New.Stats.Size,
-		pkg.Old.Stats.DownloadsLastWeek,
-		pkg.New.Stats.TotalDownloads,
-		pkg.Old.Stats.Subdependencies,
-		pkg.New.Stats.Subdependencies,
-	)
+	if err := writeReport(newVersionReport(pkg)); err != nil {
+		log.Fatal().Err(err).Msg("Failed to write report")
+	}
 }
 
 func promptPackageVersions(npmClient *npm.Client) *packageVersionsInfo {
-	packageName, err := internal.RunPrompt(&promptui.Prompt{Label: "Package"})
-	if err != nil {
-		log.Fatal().Err(err).Msg("Prompt failed")
-	}
-
-	log.Info().Str("package", packageName).Msg("Fetching package info")
-
 	b := &packageVersionsInfo{
 		Old: packageInfo{},
 		New: packageInfo{},
 	}
 
-	info, err := npmClient.GetPackageInfo(packageName)
-	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to fetch package info")
-	}
+	info, version := promptPackageSpec(npmClient)
 
 	b.Old.Info = info
 	b.New.Info = info
 
-	log.Debug().Msgf("Fetched package info for %s", info.Name)
-
-	oldPackageVersion := promptPackageVersion(info, "Select the old version")
-	b.Old.Package = info.Versions[oldPackageVersion]
+	// A version given together with the package name is used as the old version
+	if version != nil {
+		b.Old.Package = *version
+	} else {
+		b.Old.Package = info.Versions[promptPackageVersion(info, "Select the old version")]
+	}
+	oldPackageVersion := b.Old.Package.Version.String()
 	log.Info().Str("version", oldPackageVersion).Msg("Selected old version")
 
 	newPackageVersion := promptPackageVersion(info, "Select the new version")
@@ -104,6 +84,7 @@ func promptPackageVersions(npmClient *npm.Client) *packageVersionsInfo {
 	go func() {
 		defer wg.Done()
 
+		var err error
 		oldStats.Size, b.Old.TmpDir, err = measurePackageSize(b.Old.AsDependency())
 		if err != nil {
 			log.Fatal().Err(err).Msg("Failed to measure old package size")
@@ -115,11 +96,19 @@ func promptPackageVersions(npmClient *npm.Client) *packageVersionsInfo {
 		}
 
 		oldStats.Subdependencies = getSubdependenciesCount(b.Old.Lockfile)
+		b.Old.Licenses = collectLicenses(b.Old.Lockfile, "")
+		b.Old.Tree = captureSizeTree(b.Old.TmpDir)
+
+		b.Old.Kinds, err = measureKindBreakdown(b.Old.TmpDir, b.Old.Lockfile, b.Old.Package.JSON.Name)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to measure old dependency kinds")
+		}
 	}()
 
 	go func() {
 		defer wg.Done()
 
+		var err error
 		newStats.Size, b.New.TmpDir, err = measurePackageSize(b.New.AsDependency())
 		if err != nil {
 			log.Fatal().Err(err).Msg("Failed to measure new package size")
@@ -131,6 +120,13 @@ func promptPackageVersions(npmClient *npm.Client) *packageVersionsInfo {
 		}
 
 		newStats.Subdependencies = getSubdependenciesCount(b.New.Lockfile)
+		b.New.Licenses = collectLicenses(b.New.Lockfile, "")
+		b.New.Tree = captureSizeTree(b.New.TmpDir)
+
+		b.New.Kinds, err = measureKindBreakdown(b.New.TmpDir, b.New.Lockfile, b.New.Package.JSON.Name)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to measure new dependency kinds")
+		}
 	}()
 
 	wg.Wait()
@@ -138,6 +134,13 @@ func promptPackageVersions(npmClient *npm.Client) *packageVersionsInfo {
 	b.Old.Stats = oldStats.Calculate()
 	b.New.Stats = newStats.Calculate()
 
+	if *fVersionMix > 0 {
+		b.New.VersionMix = measureVersionMix(info, downloads, map[string]uint64{
+			oldPackageVersion: oldStats.Size,
+			newPackageVersion: newStats.Size,
+		}, *fVersionMix)
+	}
+
 	return b
 }
 
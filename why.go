@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"package_size_calculator/pkg/npm"
+	"path/filepath"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/fatih/color"
+	"github.com/rs/zerolog/log"
+)
+
+// maxWhyPaths limits the paths shown per installed copy, packages deep in
+// big trees can be reached in thousands of ways
+const maxWhyPaths = 50
+
+// explainWhyInPackage measures a package and explains why the named package is
+// in its tree.
+func explainWhyInPackage(name string) {
+	pkg := promptPackage(npmClient)
+
+	writeWhyReport(name, npm.NewGraph(pkg.Lockfile), pkg.PackageSizes)
+}
+
+// explainWhyInProject explains why the named package is in the tree of a local
+// project. Sizes are only shown if the project's dependencies are installed.
+func explainWhyInProject(name, dir string) {
+	lock, err := npm.ParsePackageLockJSON(filepath.Join(dir, "package-lock.json"))
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse package-lock.json")
+	}
+
+	var sizes map[string]uint64
+	if _, err := os.Stat(filepath.Join(dir, "node_modules")); err == nil {
+		sizes, err = measureLockedPackageSizes(dir, lock)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to measure installed packages")
+		}
+	} else {
+		log.Warn().Str("dir", dir).Msg("Dependencies aren't installed, sizes are unavailable")
+	}
+
+	writeWhyReport(name, npm.NewGraph(lock), sizes)
+}
+
+func writeWhyReport(name string, graph *npm.Graph, sizes map[string]uint64) {
+	err := writeTextReport(func(w io.Writer) { reportWhy(w, name, graph, sizes) })
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to write report")
+	}
+}
+
+func reportWhy(w io.Writer, name string, graph *npm.Graph, sizes map[string]uint64) {
+	copies := graph.NodesNamed(name)
+
+	fmt.Fprintln(w)
+	if len(copies) == 0 {
+		boldRed.Fprintf(w, "\"%s\" is not installed\n", name)
+		return
+	}
+
+	bold.Fprintf(w, "%s is installed %s\n", boldYellow.Sprint(name), pluralize(len(copies), "time", "times"))
+
+	for _, n := range copies {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, strings.TrimSpace(fmt.Sprintf(
+			"%s %s %s",
+			boldYellow.Sprint(n.String()),
+			grayParens("%s", n.Package.Location),
+			formatNodeSize(n, sizes),
+		)))
+
+		paths := graph.PathsTo(n, npm.AllEdges, maxWhyPaths+1)
+		if len(paths) == 0 {
+			fmt.Fprintf(w, "  %s\n", gray.Sprint("Not required by any package"))
+			continue
+		}
+
+		for i, path := range paths {
+			if i == maxWhyPaths {
+				fmt.Fprintf(w, "  %s\n", gray.Sprintf("... and more paths"))
+				break
+			}
+
+			fmt.Fprintf(w, "  %s\n", formatWhyPath(path, sizes))
+		}
+	}
+}
+
+func formatWhyPath(path []*npm.GraphNode, sizes map[string]uint64) string {
+	parts := make([]string, 0, len(path))
+
+	// The root is the project or the anonymous project the package was
+	// installed in, which isn't interesting
+	for i, n := range path[1:] {
+		kind := ""
+		if edge := edgeBetween(path[i], n); edge != nil && edge.Kind != npm.DependencyKindProd {
+			kind = color.CyanString("%s ", edge.Kind)
+		}
+
+		parts = append(parts, strings.TrimSpace(fmt.Sprintf("%s%s %s", kind, n.String(), formatNodeSize(n, sizes))))
+	}
+
+	return strings.Join(parts, " "+arrow+" ")
+}
+
+func edgeBetween(from, to *npm.GraphNode) *npm.GraphEdge {
+	for _, edge := range from.Edges {
+		if edge.To == to {
+			return edge
+		}
+	}
+
+	return nil
+}
+
+func formatNodeSize(n *npm.GraphNode, sizes map[string]uint64) string {
+	size, ok := sizes[n.Path]
+	if !ok {
+		return ""
+	}
+
+	return grayParens("%s", humanize.Bytes(size))
+}
+
+func pluralize(n int, singular, plural string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, singular)
+	}
+
+	return fmt.Sprintf("%d %s", n, plural)
+}